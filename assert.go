@@ -0,0 +1,151 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+)
+
+// error 的反射类型
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// 判断值的动态类型是否满足类型断言的目标类型
+// 目标是接口类型时判断是否实现了该接口，否则要求动态类型完全一致；nil 不满足任何类型
+func assertable(value any, typ reflect.Type) bool {
+	if value == nil {
+		return false
+	}
+	if typ.Kind() == reflect.Interface {
+		return reflect.TypeOf(value).Implements(typ)
+	}
+	return reflect.TypeOf(value) == typ
+}
+
+// 类型断言的零值，接口类型为 nil
+func assertZero(typ reflect.Type) any {
+	if typ.Kind() == reflect.Interface {
+		return nil
+	}
+	return reflect.Zero(typ).Interface()
+}
+
+// 处理类型断言 x.(T)，断言失败时产生可以被recover的panic
+func (i *Interpreter) evalTypeAssertExpr(expr *ast.TypeAssertExpr) (any, error) {
+	value, typ, err := i.evalAssertOperands(expr)
+	if err != nil {
+		return nil, err
+	}
+	if !assertable(value, typ) {
+		return nil, &PanicError{
+			Value: fmt.Sprintf("interface conversion: interface {} is %s, not %s", typeName(reflect.TypeOf(value)), typeName(typ)),
+			Pos:   i.position(expr.Pos()),
+		}
+	}
+	return value, nil
+}
+
+// 处理类型断言的 comma-ok 形式 v, ok := x.(T)
+func (i *Interpreter) evalTypeAssertOk(expr *ast.TypeAssertExpr) ([]any, error) {
+	value, typ, err := i.evalAssertOperands(expr)
+	if err != nil {
+		return nil, err
+	}
+	if assertable(value, typ) {
+		return []any{value, true}, nil
+	}
+	return []any{assertZero(typ), false}, nil
+}
+
+// 计算类型断言的值和目标类型
+func (i *Interpreter) evalAssertOperands(expr *ast.TypeAssertExpr) (any, reflect.Type, error) {
+	if expr.Type == nil {
+		return nil, nil, fmt.Errorf("x.(type) 只能用于 type switch")
+	}
+	value, err := i.eval(expr.X)
+	if err != nil {
+		return nil, nil, err
+	}
+	typ, err := i.resolveType(expr.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, typ, nil
+}
+
+// 处理 type switch 语句 switch v := x.(type) {...}
+func (i *Interpreter) evalTypeSwitchStmt(stmt *ast.TypeSwitchStmt, label string) (any, error) {
+	i = i.withScope(&Scope{parent: i.scope})
+
+	if stmt.Init != nil {
+		if _, err := i.eval(stmt.Init); err != nil {
+			return nil, err
+		}
+	}
+
+	// switch x.(type) 或 switch v := x.(type)
+	var name string
+	var assert *ast.TypeAssertExpr
+	switch s := stmt.Assign.(type) {
+	case *ast.ExprStmt:
+		assert = s.X.(*ast.TypeAssertExpr)
+	case *ast.AssignStmt:
+		name = s.Lhs[0].(*ast.Ident).Name
+		assert = s.Rhs[0].(*ast.TypeAssertExpr)
+	}
+	value, err := i.eval(assert.X)
+	if err != nil {
+		return nil, err
+	}
+
+	// 依次匹配每个 case，default 在都不匹配时执行
+	var matched, defaultClause *ast.CaseClause
+	for _, stmt := range stmt.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if clause.List == nil {
+			defaultClause = clause
+			continue
+		}
+		for _, typeExpr := range clause.List {
+			ok, err := i.matchType(value, typeExpr)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matched = clause
+				break
+			}
+		}
+		if matched != nil {
+			break
+		}
+	}
+	if matched == nil {
+		matched = defaultClause
+	}
+	if matched == nil {
+		return nil, nil
+	}
+
+	// 每个 case 有自己的作用域，脚本中的值本身带有动态类型，变量直接绑定原值
+	i = i.withScope(&Scope{parent: i.scope})
+	if name != "" {
+		i.scope.Store(name, value)
+	}
+	result, err := i.evalStmtList(matched.Body)
+	if _, ok := result.(fallthroughSentinel); ok {
+		return nil, fmt.Errorf("type switch 中不能使用 fallthrough")
+	}
+	return switchControl(result, err, label)
+}
+
+// 判断值是否匹配 type switch 中 case 的类型，case nil 匹配 nil 值
+func (i *Interpreter) matchType(value any, typeExpr ast.Expr) (bool, error) {
+	if ident, ok := typeExpr.(*ast.Ident); ok && ident.Name == "nil" {
+		return value == nil, nil
+	}
+	typ, err := i.resolveType(typeExpr)
+	if err != nil {
+		return false, err
+	}
+	return assertable(value, typ), nil
+}
@@ -0,0 +1,166 @@
+package goscript
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTypeAssert(t *testing.T) {
+	bindings := map[string]any{
+		"data": map[string]any{
+			"name": "goscript",
+			"tags": []any{"a", "b"},
+			"size": 1.5,
+		},
+		"builder": &strings.Builder{},
+		"fail": func() error {
+			return errors.New("failed")
+		},
+	}
+
+	// 断言为内置类型
+	t.Run("Builtin", func(t *testing.T) {
+		result, err := newTestInterpreter(bindings).Interpret(`
+		name := data["name"].(string)
+		size := data["size"].(float64)
+		[]any{name, size}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		list := result.([]any)
+		if list[0] != "goscript" || list[1] != 1.5 {
+			t.Errorf("Unexpected result: %v", list)
+		}
+	})
+
+	// comma-ok 形式断言失败时返回零值
+	t.Run("Comma Ok", func(t *testing.T) {
+		result, err := newTestInterpreter(bindings).Interpret(`
+		n, ok := data["name"].(int)
+		s, ok2 := data["name"].(string)
+		m, ok3 := data["missing"].(any)
+		[]any{n, ok, s, ok2, m, ok3}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		list := result.([]any)
+		if list[0] != 0 || list[1] != false || list[2] != "goscript" || list[3] != true || list[4] != nil || list[5] != false {
+			t.Errorf("Unexpected result: %v", list)
+		}
+	})
+
+	// 断言失败产生可以recover的panic
+	t.Run("Failed Assertion", func(t *testing.T) {
+		interp := newTestInterpreter(bindings)
+		_, err := interp.Interpret(`
+		n := data["name"].(int)
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Fatalf("Expected *PanicError, got %v", err)
+		}
+		if p.Pos.Line != 2 {
+			t.Errorf("Expected line 2, got %v", p.Pos)
+		}
+
+		result, err := interp.Interpret(`
+		msg := ""
+		f := func() {
+			defer func() {
+				msg = recover()
+			}()
+			data["name"].(int)
+		}
+		f()
+		msg
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != "interface conversion: interface {} is string, not int" {
+			t.Errorf("Unexpected result: %v", result)
+		}
+	})
+
+	// 断言为包中的宿主类型和接口类型
+	t.Run("Host Types", func(t *testing.T) {
+		result, err := newTestInterpreter(bindings).Interpret(`
+		_, ok := builder.(*strings.Builder)
+		_, ok2 := builder.(strings.Builder)
+		e, ok3 := fail().(error)
+		[]any{ok, ok2, ok3, e.Error()}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		list := result.([]any)
+		if list[0] != true || list[1] != false || list[2] != true || list[3] != "failed" {
+			t.Errorf("Unexpected result: %v", list)
+		}
+	})
+
+	// type switch
+	t.Run("Type Switch", func(t *testing.T) {
+		result, err := newTestInterpreter(bindings).Interpret(`
+		type Point struct {
+			X int
+		}
+
+		describe := func(x any) string {
+			s := ""
+			switch v := x.(type) {
+			case nil:
+				s = "nil"
+			case int, float64:
+				s = "number"
+			case string:
+				s = "string " + v
+			case []any:
+				s = "list " + len(v)
+			case Point:
+				s = "point " + v.X
+			case *strings.Builder:
+				s = "builder"
+			default:
+				s = "other"
+			}
+			return s
+		}
+		[]any{describe(nil), describe(data["size"]), describe(data["name"]), describe(data["tags"]),
+			describe(Point{X: 3}), describe(builder), describe(true)}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		expected := []any{"nil", "number", "string goscript", "list 2", "point 3", "builder", "other"}
+		list := result.([]any)
+		for idx := range expected {
+			if list[idx] != expected[idx] {
+				t.Errorf("Expected %v at %d, got %v", expected[idx], idx, list[idx])
+			}
+		}
+	})
+
+	// default 不在最后时也只在没有匹配时执行
+	t.Run("Default First", func(t *testing.T) {
+		result, err := newTestInterpreter(bindings).Interpret(`
+		s := ""
+		switch data["name"].(type) {
+		default:
+			s = "other"
+		case string:
+			s = "string"
+		}
+		s
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != "string" {
+			t.Errorf("Expected string, got %v", result)
+		}
+	})
+}
@@ -1,17 +1,20 @@
 package goscript
 
 import (
-	"go/ast"
+	"go/token"
 	"sync"
 )
 
 type astCache struct {
 	sync.RWMutex
-	cache map[string]*ast.File
+	// 以原始脚本为键缓存编译后的程序，避免每次都预处理和包装脚本
+	cache map[string]*Program
+	// Interpret 编译的程序共用一个FileSet，这样缓存命中时仍然可以还原源码位置
+	fset *token.FileSet
 }
 
-func (c *astCache) GetIfNotExist(key string, fn func() (*ast.File, error)) (*ast.File, error) {
-	res := (func() *ast.File {
+func (c *astCache) GetIfNotExist(key string, fn func() (*Program, error)) (*Program, error) {
+	res := (func() *Program {
 		c.RLock()
 		defer c.RUnlock()
 		if value, ok := c.cache[key]; ok {
@@ -0,0 +1,147 @@
+package goscript
+
+import (
+	"reflect"
+	"testing"
+)
+
+type counterHost struct {
+	Total int64
+	Flags uint8
+	Items []int
+	Stats map[string]int
+}
+
+func TestBitwiseAndCompoundAssign(t *testing.T) {
+	// 按位运算和移位
+	t.Run("Operators", func(t *testing.T) {
+		result, err := NewInterpreter().Interpret(`
+		var b uint8 = 0xF0
+		[]any{6 & 3, 6 | 3, 6 ^ 3, 6 &^ 3, 1 << 10, -16 >> 2, ^5, b << 1, b >> 4, ^b}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		expected := []any{2, 7, 5, 4, 1024, -4, -6, uint8(0xE0), uint8(0x0F), uint8(0x0F)}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 所有复合赋值形式作用于变量
+	t.Run("Identifiers", func(t *testing.T) {
+		result, err := NewInterpreter().Interpret(`
+		x := 10
+		x += 5
+		x -= 3
+		x *= 2
+		x /= 4
+		x %= 4
+		flags := 1
+		flags |= 6
+		flags &= 5
+		flags ^= 1
+		flags <<= 3
+		flags >>= 1
+		mask := 0xFF
+		mask &^= 0x0F
+		s := "a"
+		s += "b"
+		[]any{x, flags, mask, s}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		expected := []any{2, 16, 0xF0, "ab"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 索引表达式和选择器表达式，包括宿主结构体的字段
+	t.Run("Index And Selector", func(t *testing.T) {
+		host := &counterHost{Total: 100, Flags: 1, Items: []int{1, 2}, Stats: map[string]int{"a": 1}}
+		interp := NewInterpreter()
+		interp.SetGlobal(host)
+		result, err := interp.Interpret(`
+		m := map[string]any{}
+		m["count"] += 1
+		m["count"] += 1
+		list := []any{1, 2}
+		list[1] *= 10
+		list[0]++
+		G.Total -= 40
+		G.Flags |= 4
+		G.Items[1] += 5
+		G.Stats["a"] <<= 2
+		G.Stats["b"]--
+		[]any{m["count"], list}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		expected := []any{2, []any{2, 20}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+		if host.Total != 60 || host.Flags != 5 || host.Items[1] != 7 || host.Stats["a"] != 4 || host.Stats["b"] != -1 {
+			t.Errorf("Unexpected host: %+v", host)
+		}
+	})
+
+	// 左值中的索引和接收者只计算一次
+	t.Run("Side Effects Once", func(t *testing.T) {
+		for _, backend := range []Backend{BackendTree, BackendClosure, BackendBytecode} {
+			interp := NewInterpreter()
+			interp.SetBackend(backend)
+			result, err := interp.Interpret(`
+			type Counter struct {
+				N int
+			}
+			calls := 0
+			next := func() int {
+				calls++
+				return calls - 1
+			}
+			xs := []int{10, 20, 30}
+			xs[next()] += 1
+			xs[next()]++
+			c := &Counter{}
+			get := func() *Counter {
+				calls++
+				return c
+			}
+			get().N += 5
+			n := 1
+			ptr := func() *int {
+				calls++
+				return &n
+			}
+			*ptr() *= 3
+			[]any{xs, c.N, n, calls}
+			`)
+			if err != nil {
+				t.Fatalf("%v: Error: %v", backend, err)
+			}
+			expected := []any{[]any{11, 21, 30}, 5, 3, 4}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("%v: Expected %v, got %v", backend, expected, result)
+			}
+		}
+	})
+
+	// 无效的操作返回错误
+	t.Run("Errors", func(t *testing.T) {
+		for _, code := range []string{
+			`1.5 & 1`,
+			`1 << -1`,
+			`x := 1.0
+			x <<= 1`,
+			`"a" | 1`,
+		} {
+			if _, err := NewInterpreter().Interpret(code); err == nil {
+				t.Errorf("Expected error for %s", code)
+			}
+		}
+	})
+}
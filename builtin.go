@@ -0,0 +1,396 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+// 需要由解释器直接处理的内置函数：参数是类型（make、new），需要访问调用帧（panic、recover），
+// 或者需要把错误作为脚本错误返回。len、print 等普通内置函数在 libs 中以宿主函数的形式注册
+var builtins = map[string]bool{
+	"panic":   true,
+	"recover": true,
+	"make":    true,
+	"new":     true,
+	"append":  true,
+	"copy":    true,
+	"delete":  true,
+	"cap":     true,
+	"min":     true,
+	"max":     true,
+	"clear":   true,
+	"close":   true,
+}
+
+// 处理内置函数调用
+func (i *Interpreter) evalBuiltin(call *ast.CallExpr, name string) (any, error) {
+	switch name {
+	case "panic", "recover":
+		return i.evalPanicRecover(call, name)
+	case "make":
+		return i.evalMake(call)
+	case "new":
+		if len(call.Args) != 1 {
+			return nil, fmt.Errorf("new 需要一个类型参数")
+		}
+		typ, err := i.resolveType(call.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.New(typ).Interface(), nil
+	}
+
+	args, err := i.evalArgs(call.Args)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "append":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("append 需要至少一个参数")
+		}
+		elems := args[1:]
+		if call.Ellipsis.IsValid() {
+			// append(s, other...)
+			if len(args) != 2 {
+				return nil, fmt.Errorf("append 使用 ... 时只能有两个参数")
+			}
+			if elems, err = spreadValues(args[1]); err != nil {
+				return nil, err
+			}
+		}
+		return appendValues(args[0], elems)
+	case "copy":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("copy 需要两个参数")
+		}
+		return copyValues(args[0], args[1])
+	case "delete":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("delete 需要两个参数")
+		}
+		return nil, deleteKey(args[0], args[1])
+	case "cap":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("cap 需要一个参数")
+		}
+		if args[0] == nil {
+			return 0, nil
+		}
+		v := reflect.ValueOf(args[0])
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Chan:
+			return v.Cap(), nil
+		}
+		return nil, fmt.Errorf("cap 的参数无效: %s", typeName(v.Type()))
+	case "min", "max":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s 需要至少一个参数", name)
+		}
+		op := token.LSS
+		if name == "max" {
+			op = token.GTR
+		}
+		// 参数是多返回值展开的结果时没有对应的表达式
+		exprs := call.Args
+		if len(exprs) != len(args) {
+			exprs = make([]ast.Expr, len(args))
+		}
+		result, resultExpr := args[0], exprs[0]
+		for idx, arg := range args[1:] {
+			better, err := comparison(op, arg, result, i.untypedOperands(arg, result, exprs[idx+1], resultExpr))
+			if err != nil {
+				return nil, err
+			}
+			if better {
+				result, resultExpr = arg, exprs[idx+1]
+			}
+		}
+		// 与go一样，结果为所有参数的共同类型，如 min(1, 2.5) 得到 1.0
+		if t := i.commonNumericType(args, exprs); t != nil {
+			result = reflect.ValueOf(result).Convert(t).Interface()
+		}
+		return result, nil
+	case "clear":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("clear 需要一个参数")
+		}
+		return nil, clearValue(args[0])
+	case "close":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("close 需要一个参数")
+		}
+		return nil, i.closeChan(call, args[0])
+	}
+	return nil, fmt.Errorf("未知的内置函数: %s", name)
+}
+
+// 处理 make，脚本中的切片和map使用 []any 和 map[string]any（键不是字符串时为 map[any]any），
+// 切片的元素初始化为元素类型的零值；channel 使用元素类型的 reflect channel
+func (i *Interpreter) evalMake(call *ast.CallExpr) (any, error) {
+	if len(call.Args) == 0 {
+		return nil, fmt.Errorf("make 需要至少一个参数")
+	}
+	sizes := make([]int, 0, 2)
+	for _, arg := range call.Args[1:] {
+		value, err := i.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		size, ok := toInt(value)
+		if !ok {
+			return nil, fmt.Errorf("make 的大小参数必须是整数，得到: %T", value)
+		}
+		if size < 0 {
+			return nil, fmt.Errorf("make 的大小参数不能为负数: %d", size)
+		}
+		sizes = append(sizes, size)
+	}
+
+	switch t := call.Args[0].(type) {
+	case *ast.MapType:
+		if len(sizes) > 1 {
+			return nil, fmt.Errorf("make map 最多只能有一个大小参数")
+		}
+		keyType, err := i.resolveType(t.Key)
+		if err != nil {
+			return nil, err
+		}
+		if keyType.Kind() == reflect.String {
+			return make(map[string]any), nil
+		}
+		return make(map[any]any), nil
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return nil, fmt.Errorf("make 不能用于数组类型")
+		}
+		if len(sizes) == 0 {
+			return nil, fmt.Errorf("make 切片需要长度参数")
+		}
+		length, capacity := sizes[0], sizes[0]
+		if len(sizes) > 1 {
+			capacity = sizes[1]
+		}
+		if len(sizes) > 2 || capacity < length {
+			return nil, fmt.Errorf("make 切片的参数无效: 长度 %d, 容量 %d", length, capacity)
+		}
+		elemType, err := i.resolveType(t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		slice := make([]any, length, capacity)
+		zero := assertZero(elemType)
+		for idx := range slice {
+			slice[idx] = zero
+		}
+		return slice, nil
+	case *ast.ChanType:
+		if len(sizes) > 1 {
+			return nil, fmt.Errorf("make channel 最多只能有一个缓冲区大小参数")
+		}
+		typ, err := i.resolveType(t)
+		if err != nil {
+			return nil, err
+		}
+		if typ.ChanDir() != reflect.BothDir {
+			return nil, fmt.Errorf("make 不能创建单向channel: %s", typ)
+		}
+		buffer := 0
+		if len(sizes) > 0 {
+			buffer = sizes[0]
+		}
+		return reflect.MakeChan(typ, buffer).Interface(), nil
+	default:
+		return nil, fmt.Errorf("不支持的 make 类型: %T", t)
+	}
+}
+
+// 展开 f(xs...) 中的切片参数，字符串展开为字节
+func spreadValues(value any) ([]any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return v, nil
+	case string:
+		value = []byte(v)
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("只能展开切片，得到: %s", typeName(v.Type()))
+	}
+	values := make([]any, v.Len())
+	for idx := range values {
+		values[idx] = v.Index(idx).Interface()
+	}
+	return values, nil
+}
+
+// append 支持 []any 和宿主的类型化切片，追加到类型化切片的元素会转换为切片的元素类型
+func appendValues(slice any, elems []any) (any, error) {
+	switch s := slice.(type) {
+	case nil:
+		return append([]any{}, elems...), nil
+	case []any:
+		return append(s, elems...), nil
+	}
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("append 的第一个参数必须是切片，得到: %s", typeName(v.Type()))
+	}
+	for _, elem := range elems {
+		value, err := toValue(elem, v.Type().Elem())
+		if err != nil {
+			return nil, err
+		}
+		v = reflect.Append(v, value)
+	}
+	return v.Interface(), nil
+}
+
+// copy 返回复制的元素个数，源可以是字符串
+func copyValues(dst, src any) (int, error) {
+	d := reflect.ValueOf(dst)
+	if dst == nil {
+		return 0, nil
+	}
+	if d.Kind() != reflect.Slice {
+		return 0, fmt.Errorf("copy 的目标必须是切片，得到: %s", typeName(d.Type()))
+	}
+	if str, ok := src.(string); ok {
+		src = []byte(str)
+	}
+	if src == nil {
+		return 0, nil
+	}
+	s := reflect.ValueOf(src)
+	if s.Kind() != reflect.Slice && s.Kind() != reflect.Array {
+		return 0, fmt.Errorf("copy 的源必须是切片，得到: %s", typeName(s.Type()))
+	}
+	if s.Type().Elem().AssignableTo(d.Type().Elem()) {
+		return reflect.Copy(d, s), nil
+	}
+	// 元素类型不同时逐个转换，先转换完再写入，以正确处理源和目标重叠的情况
+	n := d.Len()
+	if s.Len() < n {
+		n = s.Len()
+	}
+	values := make([]reflect.Value, n)
+	for idx := range values {
+		value, err := toValue(s.Index(idx).Interface(), d.Type().Elem())
+		if err != nil {
+			return 0, err
+		}
+		values[idx] = value
+	}
+	for idx, value := range values {
+		d.Index(idx).Set(value)
+	}
+	return n, nil
+}
+
+// 删除map中的键，nil map和不存在的键不做任何操作
+func deleteKey(m, key any) error {
+	switch c := m.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		strKey, ok := key.(string)
+		if !ok {
+			return fmt.Errorf("map键必须是字符串类型，得到: %T", key)
+		}
+		delete(c, strKey)
+		return nil
+	case map[any]any:
+		delete(c, key)
+		return nil
+	}
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		return fmt.Errorf("delete 的第一个参数必须是map，得到: %s", typeName(v.Type()))
+	}
+	keyValue, err := toValue(key, v.Type().Key())
+	if err != nil {
+		return err
+	}
+	v.SetMapIndex(keyValue, reflect.Value{})
+	return nil
+}
+
+// min、max 的参数都是数值时，按 numericType 的规则（无类型常量采用另一个参数的类型）得到的共同类型
+func (i *Interpreter) commonNumericType(args []any, exprs []ast.Expr) reflect.Type {
+	var t reflect.Type
+	untyped := false
+	for idx, arg := range args {
+		if !isNumber(arg) {
+			return nil
+		}
+		argType, argUntyped := reflect.TypeOf(arg), i.isUntyped(exprs[idx])
+		if t == nil {
+			t, untyped = argType, argUntyped
+			continue
+		}
+		var operands untypedOperands
+		if untyped {
+			operands |= untypedLeft
+		}
+		if argUntyped {
+			operands |= untypedRight
+		}
+		next, err := numericType(t, argType, operands)
+		if err != nil {
+			return nil
+		}
+		t, untyped = next, untyped && argUntyped
+	}
+	return t
+}
+
+// 脚本中的 []any 切片元素的零值：不为 nil 的元素都是同一类型时为该类型的零值（如 []int{1, 2} 为 0），否则为 nil
+func elemZero(items []any) any {
+	var typ reflect.Type
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if typ != nil && reflect.TypeOf(item) != typ {
+			return nil
+		}
+		typ = reflect.TypeOf(item)
+	}
+	if typ == nil {
+		return nil
+	}
+	return assertZero(typ)
+}
+
+// clear 删除map中的所有键，或将切片的所有元素置为零值
+func clearValue(value any) error {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			v.SetMapIndex(key, reflect.Value{})
+		}
+		return nil
+	case reflect.Slice:
+		if items, ok := value.([]any); ok {
+			zero := elemZero(items)
+			for idx := range items {
+				items[idx] = zero
+			}
+			return nil
+		}
+		zero := reflect.Zero(v.Type().Elem())
+		for idx := 0; idx < v.Len(); idx++ {
+			v.Index(idx).Set(zero)
+		}
+		return nil
+	}
+	return fmt.Errorf("clear 的参数必须是map或切片，得到: %s", typeName(v.Type()))
+}
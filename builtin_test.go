@@ -0,0 +1,156 @@
+package goscript
+
+import (
+	"reflect"
+	"testing"
+)
+
+type builtinHost struct {
+	Tags   []string
+	Scores map[string]int
+}
+
+func TestBuiltins(t *testing.T) {
+	// append 到脚本切片，包括展开另一个切片
+	t.Run("Append", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		var s []int
+		s = append(s, 1, 2)
+		s = append(s, []any{3, 4}...)
+		s
+		`)
+		if !reflect.DeepEqual(result, []int{1, 2, 3, 4}) {
+			t.Errorf("Expected [1 2 3 4], got %#v", result)
+		}
+	})
+
+	// 宿主结构体中的类型化切片和map
+	t.Run("Typed Host Containers", func(t *testing.T) {
+		host := &builtinHost{Tags: []string{"a"}, Scores: map[string]int{"x": 1, "y": 2}}
+		interp := NewInterpreter()
+		interp.SetGlobal(host)
+		_, err := interp.Interpret(`
+		G.Tags = append(G.Tags, "b", "c")
+		delete(G.Scores, "x")
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(host.Tags, []string{"a", "b", "c"}) {
+			t.Errorf("Expected [a b c], got %v", host.Tags)
+		}
+		if !reflect.DeepEqual(host.Scores, map[string]int{"y": 2}) {
+			t.Errorf("Expected map[y:2], got %v", host.Scores)
+		}
+
+		_, err = interp.Interpret(`G.Tags = append(G.Tags, 1)`)
+		if err == nil {
+			t.Errorf("Expected error appending int to []string")
+		}
+	})
+
+	// make 按类型初始化，支持长度和容量
+	t.Run("Make", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		s := make([]int, 2, 5)
+		m := make(map[int]string)
+		m[1] = "one"
+		[]any{s, len(s), cap(s), m[1]}
+		`)
+		expected := []any{[]any{0, 0}, 2, 5, "one"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// copy 和 clear
+	t.Run("Copy And Clear", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		dst := make([]int, 2)
+		n := copy(dst, []any{7, 8, 9})
+		m := map[string]any{"a": 1}
+		clear(m)
+		xs := []int{1, 2}
+		clear(xs)
+		ys := []any{1, "a"}
+		clear(ys)
+		[]any{n, dst, len(m), xs, ys}
+		`)
+		expected := []any{2, []any{7, 8}, 0, []any{0, 0}, []any{nil, nil}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// new 返回指向零值的指针
+	t.Run("New", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		type Point struct {
+			X int
+		}
+		new(Point)
+		`)
+		v := reflect.ValueOf(result)
+		if v.Kind() != reflect.Ptr || v.Elem().Field(0).Interface() != 0 {
+			t.Errorf("Expected pointer to zero Point, got %#v", result)
+		}
+	})
+
+	// min 和 max
+	t.Run("Min Max", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		x := 2
+		[]any{min(3, 1, 2), max(3, 1, 2), min("b", "a"), max(1, 2.5), min(1, 2.5), min(x, 3)}
+		`)
+		expected := []any{1, 3, "a", 2.5, 1.0, 2}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 参数错误作为脚本错误返回
+	t.Run("Errors", func(t *testing.T) {
+		for _, code := range []string{
+			`append(1, 2)`,
+			`make([]int)`,
+			`make([]int, 3, 1)`,
+			`delete([]any{}, 1)`,
+			`min()`,
+		} {
+			if _, err := NewInterpreter().Interpret(code); err == nil {
+				t.Errorf("Expected error for %s", code)
+			}
+		}
+	})
+
+	// 脚本中声明的和宿主设置的同名变量覆盖内置函数
+	t.Run("Shadowed", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		func copy(s string) string {
+			return s + s
+		}
+		max := func(a, b int) int { return 42 }
+		[]any{max(1, 2), copy("ab"), min(3, 1)}
+		`)
+		if !reflect.DeepEqual(result, []any{42, "abab", 1}) {
+			t.Errorf("Expected [42 abab 1], got %v", result)
+		}
+
+		interp := NewInterpreter()
+		var closed []string
+		interp.Set("close", func(name string) {
+			closed = append(closed, name)
+		})
+		interp.Set("min", func(a, b string) string { return a })
+		result, err := interp.Interpret(`
+		close("db")
+		min("x", "a")
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != "x" || !reflect.DeepEqual(closed, []string{"db"}) {
+			t.Errorf("Expected host bindings to be called, got %v %v", result, closed)
+		}
+	})
+}
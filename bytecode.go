@@ -0,0 +1,699 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// opcode 字节码指令
+type opcode uint8
+
+const (
+	opConst     opcode = iota // 压入常量 consts[a]
+	opLoad                    // 压入变量 idents[a] 的值
+	opGlobal                  // 压入 global 对象
+	opPop                     // 弹出栈顶的值
+	opSingle                  // 检查栈顶的值不是多返回值
+	opBinary                  // 弹出两个操作数，压入运算 binops[a] 的结果
+	opAssign                  // 弹出 assigns[a] 中左值数量的值并依次赋值
+	opCompound                // 弹出左值当前的值和右侧的值，按 binops[a] 计算后赋给 assigns[b]
+	opUpdate                  // 弹出右侧的值，读取 assigns[b] 中左值的当前值，按 binops[a] 计算后写回
+	opSelect                  // 弹出对象，压入选择器表达式 nodes[a] 选择的字段或方法
+	opIndex                   // 弹出容器和索引，压入索引表达式 nodes[a] 的值
+	opUnary                   // 弹出操作数，压入一元运算 a 的结果
+	opAddr                    // 压入表达式 nodes[a] 的地址
+	opRecv                    // 弹出channel，压入接收表达式 nodes[a] 接收到的值
+	opConvert                 // 栈顶是类型时，调用 calls[a] 实际是类型转换，遍历AST计算后跳转到 b
+	opMethod                  // 压入方法调用 calls[a] 的函数；调用是类型转换或者需要写回接收器时，直接完成调用，压入结果后跳转到 b
+	opCall                    // 弹出函数和 b 个参数，执行调用 calls[a]
+	opEval                    // 遍历AST计算表达式 nodes[a]
+	opExec                    // 遍历AST执行语句 nodes[a]，break 和 continue 交给外层的循环 loops[b] 处理
+	opRange                   // 弹出被遍历的值，执行 range 循环 ranges[a]，跳出外层循环的 break 和 continue 交给 loops[b] 处理
+	opBranch                  // 返回分支语句 nodes[a] 产生的控制流信号
+	opJump                    // 退回到第 b 层作用域，跳转到 a
+	opJumpFalse               // 弹出条件，条件为假时跳转到 a
+	opJumpTrue                // 弹出条件，条件为真时跳转到 a
+	opEnter                   // 进入新的作用域
+	opLeave                   // 退出当前的作用域
+	opNextIter                // for 循环的下一次迭代使用新的变量 names[a]
+	opReturn                  // 弹出 a 个值作为return的结果
+)
+
+var opNames = [...]string{
+	opConst:     "CONST",
+	opLoad:      "LOAD",
+	opGlobal:    "GLOBAL",
+	opPop:       "POP",
+	opSingle:    "SINGLE",
+	opBinary:    "BINARY",
+	opAssign:    "ASSIGN",
+	opCompound:  "COMPOUND",
+	opUpdate:    "UPDATE",
+	opSelect:    "SELECT",
+	opIndex:     "INDEX",
+	opUnary:     "UNARY",
+	opAddr:      "ADDR",
+	opRecv:      "RECV",
+	opConvert:   "CONVERT",
+	opMethod:    "METHOD",
+	opCall:      "CALL",
+	opEval:      "EVAL",
+	opExec:      "EXEC",
+	opRange:     "RANGE",
+	opBranch:    "BRANCH",
+	opJump:      "JUMP",
+	opJumpFalse: "JUMPF",
+	opJumpTrue:  "JUMPT",
+	opEnter:     "ENTER",
+	opLeave:     "LEAVE",
+	opNextIter:  "NEXTITER",
+	opReturn:    "RETURN",
+}
+
+func (op opcode) String() string {
+	return opNames[op]
+}
+
+// instr 一条指令，a、b 的含义由指令决定
+type instr struct {
+	op   opcode
+	a, b int
+}
+
+// binop 预先绑定了运算函数的二元运算符
+type binop struct {
+	op    token.Token
+	apply func(i *Interpreter, a, b any) (any, error)
+}
+
+// assignTarget 赋值的左值
+type assignTarget struct {
+	lhs    []ast.Expr
+	define bool
+}
+
+// loopTarget 编译为跳转指令的 for 循环
+type loopTarget struct {
+	// break 和 continue 跳转的位置
+	breakPC, continuePC int
+	// 循环所在的作用域层数
+	depth int
+	// 循环的标签，没有标签时为空
+	label string
+}
+
+// rangeTarget 编译过的 range 循环，循环体编译为单独的一段字节码
+type rangeTarget struct {
+	stmt  *ast.RangeStmt
+	label string
+}
+
+// chunk 一条语句编译后的字节码
+type chunk struct {
+	stmt ast.Stmt
+	code []instr
+	// 每条指令所在的语句列表中的语句，与 evalStmtList 一样用于确定运行时panic的位置
+	stmts   []ast.Stmt
+	consts  []any
+	idents  []*ast.Ident
+	binops  []binop
+	assigns []assignTarget
+	calls   []*ast.CallExpr
+	nodes   []ast.Node
+	names   [][]string
+	ranges  []rangeTarget
+	// EXEC 和 RANGE 指令外层的循环，最内层的在最后
+	loops [][]*loopTarget
+	// 遍历AST执行的节点、range 的循环体和函数字面量中的语句编译出的字节码
+	nested []*chunk
+}
+
+// bytecodeProgram 程序编译后的字节码
+type bytecodeProgram struct {
+	// 以语句为键保存的字节码，eval 遇到这些语句时在虚拟机中执行
+	chunks map[ast.Node]*chunk
+	code   map[ast.Node]evalFunc
+}
+
+// 编译程序中的函数体，函数体中的每条语句编译为一段字节码，嵌套的语句直接编译在其中
+// 通过 EXEC、EVAL 遍历AST执行的节点中的语句也会编译，遍历AST时仍然可以使用字节码执行
+func compileBytecode(prog *Program) *bytecodeProgram {
+	p := &bytecodeProgram{
+		chunks: make(map[ast.Node]*chunk),
+		code:   make(map[ast.Node]evalFunc),
+	}
+	for _, decl := range append([]*ast.FuncDecl{prog.main}, prog.funcs...) {
+		for _, stmt := range decl.Body.List {
+			p.compile(nil, stmt)
+		}
+	}
+	return p
+}
+
+// 编译一条语句，每条语句只编译一次，parent 是语句所在的字节码
+func (p *bytecodeProgram) compile(parent *chunk, stmt ast.Stmt) {
+	if _, ok := p.chunks[stmt]; ok {
+		return
+	}
+	b := &chunkBuilder{program: p, chunk: &chunk{stmt: stmt}, current: stmt}
+	p.chunks[stmt] = b.chunk
+	if parent != nil {
+		parent.nested = append(parent.nested, b.chunk)
+	}
+	b.stmt(stmt)
+	for _, patch := range b.patches {
+		if patch.isBreak {
+			b.code[patch.pc].a = patch.loop.breakPC
+		} else {
+			b.code[patch.pc].a = patch.loop.continuePC
+		}
+	}
+	p.code[stmt] = b.chunk.run
+}
+
+// 编译遍历AST执行的节点中的语句。遍历AST时通过 eval 执行的语句各自编译为一段字节码，其中嵌套的语句直接编译在其中
+func (p *bytecodeProgram) compileNested(parent *chunk, node ast.Node) {
+	walkedNodes(node, func(stmt ast.Stmt) {
+		p.compile(parent, stmt)
+	}, func(lit *ast.FuncLit) {
+		// 函数体中的语句由 evalFuncBody 逐条执行
+		for _, stmt := range lit.Body.List {
+			p.compile(parent, stmt)
+		}
+	})
+}
+
+// chunkBuilder 编译一段字节码
+type chunkBuilder struct {
+	*chunk
+	program *bytecodeProgram
+	// 正在编译的语句列表中的语句
+	current ast.Stmt
+	// 当前的作用域层数
+	depth int
+	// 正在编译的循环，最内层的在最后
+	loops []*loopTarget
+	// break 和 continue 的跳转位置在循环编译完后才能确定
+	patches []loopPatch
+}
+
+type loopPatch struct {
+	pc      int
+	loop    *loopTarget
+	isBreak bool
+}
+
+func (b *chunkBuilder) emit(op opcode, a, c int) int {
+	b.code = append(b.code, instr{op: op, a: a, b: c})
+	b.stmts = append(b.stmts, b.current)
+	return len(b.code) - 1
+}
+
+// 下一条指令的位置
+func (b *chunkBuilder) pc() int {
+	return len(b.code)
+}
+
+func (b *chunkBuilder) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.ExprStmt:
+		b.expr(s.X)
+		b.emit(opPop, 0, 0)
+	case *ast.BlockStmt:
+		if hasLabel(s.List) {
+			// goto 的目标由 evalStmtList 处理
+			b.exec(s)
+			return
+		}
+		b.emit(opEnter, 0, 0)
+		b.depth++
+		current := b.current
+		for _, stmt := range s.List {
+			b.current = stmt
+			b.stmt(stmt)
+		}
+		b.current = current
+		b.depth--
+		b.emit(opLeave, 0, 0)
+	case *ast.AssignStmt:
+		b.assignStmt(s)
+	case *ast.IncDecStmt:
+		op := token.ADD
+		if s.Tok == token.DEC {
+			op = token.SUB
+		}
+		b.emit(opConst, b.constant(1), 0)
+		b.compound(s.X, op, incDecOperand)
+	case *ast.IfStmt:
+		b.ifStmt(s)
+	case *ast.ForStmt:
+		b.forStmt(s, "")
+	case *ast.RangeStmt:
+		b.rangeStmt(s, "")
+	case *ast.LabeledStmt:
+		// 与 evalLabeledStmt 相同，标签作用于其后的循环，带标签的 switch 和 select 遍历AST执行
+		switch inner := s.Stmt.(type) {
+		case *ast.ForStmt:
+			b.forStmt(inner, s.Label.Name)
+		case *ast.RangeStmt:
+			b.rangeStmt(inner, s.Label.Name)
+		case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			b.exec(s)
+		default:
+			// 只作为 goto 的目标，由所在的语句列表处理
+			b.stmt(inner)
+		}
+	case *ast.ReturnStmt:
+		for _, result := range s.Results {
+			b.expr(result)
+		}
+		b.emit(opReturn, len(s.Results), 0)
+	case *ast.BranchStmt:
+		b.branchStmt(s)
+	default:
+		b.exec(s)
+	}
+}
+
+// break 和 continue 的目标是当前字节码中的循环时编译为跳转，
+// 否则与 evalBranchStmt 相同返回控制流信号，由外层的循环或语句列表处理
+func (b *chunkBuilder) branchStmt(s *ast.BranchStmt) {
+	if s.Tok == token.BREAK || s.Tok == token.CONTINUE {
+		for idx := len(b.loops) - 1; idx >= 0; idx-- {
+			loop := b.loops[idx]
+			if s.Label != nil && s.Label.Name != loop.label {
+				continue
+			}
+			pc := b.emit(opJump, 0, loop.depth)
+			b.patches = append(b.patches, loopPatch{pc: pc, loop: loop, isBreak: s.Tok == token.BREAK})
+			return
+		}
+	}
+	b.emit(opBranch, b.node(s), 0)
+}
+
+// 语句列表中是否有带标签的语句
+func hasLabel(list []ast.Stmt) bool {
+	for _, stmt := range list {
+		if _, ok := stmt.(*ast.LabeledStmt); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 遍历AST执行语句，语句产生的 break 和 continue 交给外层的循环处理
+func (b *chunkBuilder) exec(stmt ast.Stmt) {
+	b.emit(opExec, b.node(stmt), b.enclosingLoops())
+	b.program.compileNested(b.chunk, stmt)
+}
+
+// 记录当前外层的循环，返回它们在 loops 中的位置
+func (b *chunkBuilder) enclosingLoops() int {
+	loops := make([]*loopTarget, len(b.loops))
+	copy(loops, b.loops)
+	b.chunk.loops = append(b.chunk.loops, loops)
+	return len(b.chunk.loops) - 1
+}
+
+// 与 evalAssignStmt 相同，只编译左右两侧数量相同的赋值和复合赋值
+func (b *chunkBuilder) assignStmt(s *ast.AssignStmt) {
+	switch s.Tok {
+	case token.DEFINE, token.ASSIGN:
+		if len(s.Lhs) != len(s.Rhs) {
+			b.exec(s)
+			return
+		}
+		for _, rhs := range s.Rhs {
+			b.expr(rhs)
+		}
+		b.assigns = append(b.assigns, assignTarget{lhs: s.Lhs, define: s.Tok == token.DEFINE})
+		b.emit(opAssign, len(b.assigns)-1, 0)
+		return
+	}
+	op, ok := compoundOps[s.Tok]
+	if !ok || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+		b.exec(s)
+		return
+	}
+	b.expr(s.Rhs[0])
+	b.emit(opSingle, 0, 0)
+	b.compound(s.Lhs[0], op, s.Rhs[0])
+}
+
+// 右侧的值已经在栈上，读取左值的当前值后计算并赋值
+// 变量以外的左值由 opUpdate 读取和写回，其中的容器和索引只计算一次
+func (b *chunkBuilder) compound(lhs ast.Expr, op token.Token, rhs ast.Expr) {
+	b.assigns = append(b.assigns, assignTarget{lhs: []ast.Expr{lhs}})
+	if ident, ok := lhs.(*ast.Ident); !ok || ident.Name == "_" {
+		b.emit(opUpdate, b.binop(op, lhs, rhs), len(b.assigns)-1)
+		return
+	}
+	b.expr(lhs)
+	b.emit(opCompound, b.binop(op, lhs, rhs), len(b.assigns)-1)
+}
+
+// 与 evalIfStmt 相同
+func (b *chunkBuilder) ifStmt(s *ast.IfStmt) {
+	if s.Init != nil {
+		b.emit(opEnter, 0, 0)
+		b.depth++
+		b.stmt(s.Init)
+	}
+	b.expr(s.Cond)
+	jumpElse := b.emit(opJumpFalse, 0, 0)
+	b.stmt(s.Body)
+	if s.Else != nil {
+		jumpEnd := b.emit(opJump, 0, b.depth)
+		b.code[jumpElse].a = b.pc()
+		b.stmt(s.Else)
+		b.code[jumpEnd].a = b.pc()
+	} else {
+		b.code[jumpElse].a = b.pc()
+	}
+	if s.Init != nil {
+		b.depth--
+		b.emit(opLeave, 0, 0)
+	}
+}
+
+// 与 evalForStmt 相同，label 是循环的标签
+func (b *chunkBuilder) forStmt(f *ast.ForStmt, label string) {
+	b.emit(opEnter, 0, 0)
+	b.depth++
+	loop := &loopTarget{depth: b.depth, label: label}
+	names := -1
+	if f.Init != nil {
+		b.stmt(f.Init)
+		if assign, ok := f.Init.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+			var defined []string
+			for _, lhs := range assign.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name != "_" {
+					defined = append(defined, ident.Name)
+				}
+			}
+			if len(defined) > 0 {
+				b.names = append(b.names, defined)
+				names = len(b.names) - 1
+			}
+		}
+	}
+
+	condPC := b.pc()
+	jumpEnd := -1
+	if f.Cond != nil {
+		b.expr(f.Cond)
+		jumpEnd = b.emit(opJumpFalse, 0, 0)
+	}
+	b.loops = append(b.loops, loop)
+	b.stmt(f.Body)
+	b.loops = b.loops[:len(b.loops)-1]
+
+	loop.continuePC = b.pc()
+	if names >= 0 {
+		b.emit(opNextIter, names, 0)
+	}
+	if f.Post != nil {
+		b.stmt(f.Post)
+	}
+	b.emit(opJump, condPC, b.depth)
+
+	loop.breakPC = b.pc()
+	if jumpEnd >= 0 {
+		b.code[jumpEnd].a = loop.breakPC
+	}
+	b.depth--
+	b.emit(opLeave, 0, 0)
+}
+
+// 与 evalRangeStmt 相同，被遍历的值在当前的字节码中计算，循环体编译为单独的一段字节码，
+// 由 rangeValue 在每次迭代的作用域中执行
+func (b *chunkBuilder) rangeStmt(s *ast.RangeStmt, label string) {
+	b.expr(s.X)
+	b.ranges = append(b.ranges, rangeTarget{stmt: s, label: label})
+	b.emit(opRange, len(b.ranges)-1, b.enclosingLoops())
+	b.program.compile(b.chunk, s.Body)
+}
+
+func (b *chunkBuilder) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		// 字面量在编译时求值
+		value, err := literalValue(e)
+		if err != nil {
+			b.eval(e)
+			return
+		}
+		b.emit(opConst, b.constant(value), 0)
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			b.emit(opConst, b.constant(true), 0)
+		case "false":
+			b.emit(opConst, b.constant(false), 0)
+		case "nil":
+			b.emit(opConst, b.constant(nil), 0)
+		case "G":
+			b.emit(opGlobal, 0, 0)
+		default:
+			b.idents = append(b.idents, e)
+			b.emit(opLoad, len(b.idents)-1, 0)
+		}
+	case *ast.ParenExpr:
+		b.expr(e.X)
+	case *ast.BinaryExpr:
+		if e.Op == token.LAND || e.Op == token.LOR {
+			b.logical(e)
+			return
+		}
+		b.expr(e.X)
+		b.expr(e.Y)
+		b.emit(opBinary, b.binop(e.Op, e.X, e.Y), 0)
+	case *ast.CallExpr:
+		b.call(e)
+	case *ast.SelectorExpr:
+		b.expr(e.X)
+		b.emit(opSelect, b.node(e), 0)
+	case *ast.IndexExpr:
+		if typeIndex(e.Index) {
+			// 泛型函数的实例化 f[int]
+			b.eval(e)
+			return
+		}
+		b.expr(e.X)
+		b.expr(e.Index)
+		b.emit(opIndex, b.node(e), 0)
+	case *ast.UnaryExpr:
+		switch e.Op {
+		case token.AND:
+			// 取地址时操作数不求值
+			b.emit(opAddr, b.node(e.X), 0)
+		case token.ARROW:
+			b.expr(e.X)
+			b.emit(opRecv, b.node(e), 0)
+		default:
+			b.expr(e.X)
+			b.emit(opUnary, int(e.Op), 0)
+		}
+	default:
+		b.eval(e)
+	}
+}
+
+// && 和 || 短路求值，左侧的值已经决定结果时跳过右侧：
+// x && y 编译为 x JUMPF; y JUMPF; CONST true; JUMP; CONST false，|| 使用 JUMPT，结果相反
+func (b *chunkBuilder) logical(e *ast.BinaryExpr) {
+	jump := opJumpFalse
+	if e.Op == token.LOR {
+		jump = opJumpTrue
+	}
+	b.expr(e.X)
+	left := b.emit(jump, 0, 0)
+	b.expr(e.Y)
+	right := b.emit(jump, 0, 0)
+	b.emit(opConst, b.constant(e.Op == token.LAND), 0)
+	end := b.emit(opJump, 0, b.depth)
+	b.code[left].a, b.code[right].a = b.pc(), b.pc()
+	b.emit(opConst, b.constant(e.Op == token.LOR), 0)
+	b.code[end].a = b.pc()
+}
+
+// 索引在语法上是类型，如 f[int]、f[[]string]，这样的索引表达式只能是泛型函数的实例化
+func typeIndex(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return basicTypes[e.Name] != nil || e.Name == "any" || e.Name == "error"
+	case *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType, *ast.StructType, *ast.StarExpr:
+		return true
+	}
+	return false
+}
+
+// 编译函数调用，内置函数、展开参数的调用和函数部分可能是类型的调用遍历AST执行
+func (b *chunkBuilder) call(call *ast.CallExpr) {
+	callee := -1
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		if _, ok := compilableCall(call); !ok {
+			b.eval(call)
+			return
+		}
+		b.calls = append(b.calls, call)
+		b.expr(fun)
+		callee = b.emit(opConvert, len(b.calls)-1, 0)
+	case *ast.SelectorExpr:
+		// 方法调用和包中的函数，如 strings.Contains
+		if call.Ellipsis.IsValid() {
+			b.eval(call)
+			return
+		}
+		b.calls = append(b.calls, call)
+		callee = b.emit(opMethod, len(b.calls)-1, 0)
+	case *ast.IndexExpr, *ast.CallExpr, *ast.FuncLit:
+		if call.Ellipsis.IsValid() {
+			b.eval(call)
+			return
+		}
+		b.calls = append(b.calls, call)
+		b.expr(fun)
+	default:
+		b.eval(call)
+		return
+	}
+	idx := len(b.calls) - 1
+	for _, arg := range call.Args {
+		b.expr(arg)
+	}
+	b.emit(opCall, idx, len(call.Args))
+	if callee >= 0 {
+		b.code[callee].b = b.pc()
+	}
+}
+
+// 遍历AST计算表达式
+func (b *chunkBuilder) eval(expr ast.Expr) {
+	b.emit(opEval, b.node(expr), 0)
+	b.program.compileNested(b.chunk, expr)
+}
+
+func (b *chunkBuilder) node(node ast.Node) int {
+	b.nodes = append(b.nodes, node)
+	return len(b.nodes) - 1
+}
+
+func (b *chunkBuilder) constant(value any) int {
+	b.consts = append(b.consts, value)
+	return len(b.consts) - 1
+}
+
+func (b *chunkBuilder) binop(op token.Token, x, y ast.Expr) int {
+	b.binops = append(b.binops, binop{op: op, apply: binaryFunc(op, x, y)})
+	return len(b.binops) - 1
+}
+
+// Disassemble 返回程序编译后的字节码的文本形式，用于调试
+// 每个函数体中的语句分别列出，range 的循环体、函数字面量和遍历AST执行的节点中的语句编译出的字节码
+// 紧跟在所在的语句之后列出；EVAL 和 EXEC 指令表示该节点遍历AST执行
+func Disassemble(prog *Program) string {
+	p := prog.bytecode()
+	var sb strings.Builder
+	for _, decl := range append([]*ast.FuncDecl{prog.main}, prog.funcs...) {
+		name := decl.Name.Name
+		if decl.Recv != nil && len(decl.Recv.List) > 0 {
+			name = "(" + types.ExprString(decl.Recv.List[0].Type) + ")." + name
+		}
+		fmt.Fprintf(&sb, "%s:\n", name)
+		for _, stmt := range decl.Body.List {
+			p.chunks[stmt].disassemble(&sb, prog)
+		}
+	}
+	return sb.String()
+}
+
+func (c *chunk) disassemble(sb *strings.Builder, prog *Program) {
+	fmt.Fprintf(sb, "  ; line %d\n", prog.fset.Position(c.stmt.Pos()).Line-scriptHeaderLines)
+	for pc, in := range c.code {
+		line := fmt.Sprintf("  %04d  %-9s%s", pc, in.op, c.operands(in, prog))
+		sb.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	for _, nested := range c.nested {
+		nested.disassemble(sb, prog)
+	}
+}
+
+// 指令的操作数的文本形式
+func (c *chunk) operands(in instr, prog *Program) string {
+	switch in.op {
+	case opConst:
+		if s, ok := c.consts[in.a].(string); ok {
+			return fmt.Sprintf("%q", s)
+		}
+		return fmt.Sprintf("%v", c.consts[in.a])
+	case opLoad:
+		return c.idents[in.a].Name
+	case opBinary:
+		return c.binops[in.a].op.String()
+	case opAssign:
+		target := c.assigns[in.a]
+		lhs := make([]string, len(target.lhs))
+		for idx, expr := range target.lhs {
+			lhs[idx] = types.ExprString(expr)
+		}
+		tok := token.ASSIGN
+		if target.define {
+			tok = token.DEFINE
+		}
+		return fmt.Sprintf("%s %s", strings.Join(lhs, ", "), tok)
+	case opCompound, opUpdate:
+		return fmt.Sprintf("%s %s=", types.ExprString(c.assigns[in.b].lhs[0]), c.binops[in.a].op)
+	case opSelect:
+		return types.ExprString(c.nodes[in.a].(ast.Expr))
+	case opUnary:
+		return token.Token(in.a).String()
+	case opAddr:
+		return types.ExprString(c.nodes[in.a].(ast.Expr))
+	case opConvert:
+		return fmt.Sprintf("%04d", in.b)
+	case opMethod:
+		return fmt.Sprintf("%s %04d", types.ExprString(c.calls[in.a].Fun), in.b)
+	case opRange:
+		r := c.ranges[in.a]
+		var vars []string
+		for _, expr := range []ast.Expr{r.stmt.Key, r.stmt.Value} {
+			if expr != nil {
+				vars = append(vars, types.ExprString(expr))
+			}
+		}
+		body := fmt.Sprintf("body line %d", prog.fset.Position(r.stmt.Body.Pos()).Line-scriptHeaderLines)
+		if len(vars) > 0 {
+			body = fmt.Sprintf("%s %s %s", strings.Join(vars, ", "), r.stmt.Tok, body)
+		}
+		if r.label != "" {
+			body = r.label + ": " + body
+		}
+		return body
+	case opBranch:
+		s := c.nodes[in.a].(*ast.BranchStmt)
+		if s.Label != nil {
+			return fmt.Sprintf("%s %s", s.Tok, s.Label.Name)
+		}
+		return s.Tok.String()
+	case opCall:
+		return fmt.Sprintf("%s %d", types.ExprString(c.calls[in.a].Fun), in.b)
+	case opEval, opExec:
+		node := c.nodes[in.a]
+		return fmt.Sprintf("%T line %d", node, prog.fset.Position(node.Pos()).Line-scriptHeaderLines)
+	case opJump:
+		return fmt.Sprintf("%04d depth %d", in.a, in.b)
+	case opJumpFalse, opJumpTrue:
+		return fmt.Sprintf("%04d", in.a)
+	case opNextIter:
+		return strings.Join(c.names[in.a], ", ")
+	case opReturn:
+		return fmt.Sprint(in.a)
+	}
+	return ""
+}
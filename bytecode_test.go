@@ -0,0 +1,256 @@
+package goscript
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 字节码执行的结果与遍历AST执行的结果相同
+func TestBytecodeBackend(t *testing.T) {
+	bindings := map[string]any{
+		"join": strings.Join,
+		"time": map[string]any{
+			"Duration": reflect.TypeOf(time.Duration(0)),
+		},
+	}
+
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"Nested Loops", `
+		count := 0
+		for i := 0; i < 10; i++ {
+			if i == 8 {
+				break
+			}
+			for j := 0; j < i; j++ {
+				if j%2 == 1 {
+					continue
+				}
+				count += j
+			}
+		}
+		count`},
+		{"Break From Switch And Range", `
+		out := []any{}
+		for i := 0; i < 6; i++ {
+			switch {
+			case i == 1:
+				continue
+			case i == 4:
+				break
+			default:
+				out = append(out, i)
+			}
+			for _, v := range []any{10, 20} {
+				if v == 20 {
+					break
+				}
+				out = append(out, v+i)
+			}
+		}
+		out`},
+		{"Labels And Goto", `
+		n := 0
+	outer:
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				if j == 2 {
+					continue outer
+				}
+				n++
+			}
+		}
+		k := 0
+	loop:
+		if k < 5 {
+			k++
+			goto loop
+		}
+		[]any{n, k}`},
+		{"Return From Loop", `
+		func find(xs []any, x int) int {
+			for i := 0; i < len(xs); i++ {
+				if xs[i] == x {
+					return i
+				}
+			}
+			return -1
+		}
+		[]any{find([]any{3, 5, 7}, 7), find([]any{}, 1)}`},
+		{"Loop Variables", `
+		fns := []any{}
+		for i := 0; i < 3; i++ {
+			fns = append(fns, func() int { return i })
+		}
+		[]any{fns[0](), fns[2]()}`},
+		{"Host Calls", `
+		parts := []string{}
+		for i := 0; i < 3; i++ {
+			parts = append(parts, "p" + i)
+		}
+		join(parts, "-")`},
+		{"Range Loops", `
+		total := 0
+		words := []string{"go", "script", "vm", "skip"}
+	outer:
+		for i, w := range words {
+			for n := range 3 {
+				if w == "skip" {
+					break outer
+				}
+				if n == 2 {
+					continue outer
+				}
+				total += i * n
+			}
+		}
+		func firstLong(ws []string) string {
+			for _, w := range ws {
+				if len(w) > 3 {
+					return w
+				}
+			}
+			return ""
+		}
+		sum := 0
+		for _, v := range map[string]int{"a": 1, "b": 2} {
+			sum += v
+		}
+		[]any{total, firstLong(words), sum}`},
+		{"Index Selector And Unary", `
+		type Counter struct {
+			Name string
+			N    int
+		}
+		func (c *Counter) Inc(by int) {
+			c.N += by
+		}
+		var c Counter
+		c.Name = "hits"
+		for i := 0; i < 3; i++ {
+			c.Inc(i)
+		}
+		m := map[string]int{}
+		keys := []string{"a", "b", "a"}
+		for i := 0; i < len(keys); i++ {
+			m[keys[i]] += 1
+		}
+		x := 5
+		p := &x
+		*p = -x
+		fns := []any{func(n int) int { return n + 1 }}
+		d := time.Duration(3)
+		[]any{strings.ToUpper(c.Name), c.N, m["a"], x, !(x > 0), fns[0](x), int64(d)}`},
+		{"Defer And Named Results", `
+		func safe() (result string) {
+			defer func() {
+				if r := recover(); r != nil {
+					result = "recovered"
+				}
+			}()
+			for i := 0; i < 3; i++ {
+				if i == 2 {
+					panic("boom")
+				}
+			}
+			return "done"
+		}
+		safe()`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := newBackendInterpreter(bindings, BackendTree).Interpret(tt.code)
+			if err != nil {
+				t.Fatalf("Tree error: %v", err)
+			}
+			got, err := newBackendInterpreter(bindings, BackendBytecode).Interpret(tt.code)
+			if err != nil {
+				t.Fatalf("Bytecode error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+
+	// 错误和panic的位置与遍历AST执行时相同
+	t.Run("Errors", func(t *testing.T) {
+		for _, code := range []string{
+			`for i := 0; i < 3; i++ { i / 0 }`,
+			`func f() (int, int) { return 1, 2 }
+			x := 0
+			x += f()`,
+			`for i := 0; i < 3; i++ {
+				panic("bad")
+			}`,
+			`xs := []any{1, 2}
+			for _, x := range xs {
+				n := x.(int)
+				n = xs[n]
+			}`,
+			`var m map[string]int
+			for i := 0; i < 3; i++ {
+				if i == 1 {
+					m["a"] = i
+				}
+			}`,
+		} {
+			_, want := newBackendInterpreter(bindings, BackendTree).Interpret(code)
+			_, got := newBackendInterpreter(bindings, BackendBytecode).Interpret(code)
+			if want == nil || got == nil || got.Error() != want.Error() {
+				t.Errorf("Expected error %v, got %v", want, got)
+			}
+		}
+	})
+
+	// 反汇编列出每个函数的字节码
+	t.Run("Disassemble", func(t *testing.T) {
+		prog, err := Compile(`
+		func add(a, b int) int {
+			return a + b
+		}
+		sum := 0
+		for i := 0; i < 10; i++ {
+			if i%2 == 0 {
+				continue
+			}
+			sum = add(sum, i)
+		}
+		m := map[string]any{"sum": sum}
+		for _, k := range []string{"a", "b"} {
+			m[k] = strings.ToUpper(k)
+		}
+		double := func(n int) int { return n * 2 }
+		m["double"] = double(-sum)
+		m`)
+		if err != nil {
+			t.Fatalf("Compile error: %v", err)
+		}
+		out := Disassemble(prog)
+		for _, want := range []string{
+			"__main__:\n  ; line 5\n  0000  CONST    0\n  0001  ASSIGN   sum :=\n",
+			"BINARY   <",
+			"JUMP     0024 depth 1",
+			"NEXTITER i",
+			"CALL     add 2",
+			"EVAL     *ast.CompositeLit line 12",
+			"RANGE    _, k := body line 13",
+			"METHOD   strings.ToUpper 0004",
+			"UNARY    -",
+			"  ; line 16\n  0000  LOAD     n\n  0001  CONST    2\n  0002  BINARY   *\n  0003  RETURN   1\n",
+			"add:\n  ; line 3\n  0000  LOAD     a\n  0001  LOAD     b\n  0002  BINARY   +\n  0003  RETURN   1\n",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected disassembly to contain %q, got:\n%s", want, out)
+			}
+		}
+	})
+}
+
+func BenchmarkBytecodeBackend(b *testing.B) {
+	benchmarkBackend(b, BackendBytecode)
+}
@@ -0,0 +1,188 @@
+package goscript
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestClosures(t *testing.T) {
+	var saved func(string) string
+	bindings := map[string]any{
+		"sortSlice": sort.Slice,
+		"register": func(cb func(string) string) {
+			saved = cb
+		},
+		"each": func(items []string, cb func(int, string) error) error {
+			for idx, item := range items {
+				if err := cb(idx, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		"newError": errors.New,
+	}
+	// 闭包捕获定义时的变量，多个闭包互不影响
+	t.Run("Counters", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		func newCounter() any {
+			count := 0
+			return func() int {
+				count++
+				return count
+			}
+		}
+		a := newCounter()
+		b := newCounter()
+		a()
+		a()
+		b()
+		[]any{a(), b()}
+		`)
+		if !reflect.DeepEqual(result, []any{3, 2}) {
+			t.Errorf("Expected [3 2], got %v", result)
+		}
+	})
+
+	// 闭包看到的是定义时的变量，而不是调用者的变量
+	t.Run("Lexical Scope", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		name := "main"
+		get := func() string {
+			return name
+		}
+		func capture() any {
+			name := "captured"
+			return func() string {
+				return name
+			}
+		}
+		call := func(f any) string {
+			name := "caller"
+			return f() + "/" + name
+		}
+		[]any{call(get), call(capture())}
+		`)
+		if !reflect.DeepEqual(result, []any{"main/caller", "captured/caller"}) {
+			t.Errorf("Unexpected result: %v", result)
+		}
+	})
+
+	// 闭包实现的记忆化
+	t.Run("Memoize", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		calls := 0
+		memoize := func(f any) any {
+			cache := map[string]any{}
+			return func(n int) int {
+				key := "" + n
+				if v, ok := cache[key]; ok {
+					return v
+				}
+				v := f(n)
+				cache[key] = v
+				return v
+			}
+		}
+		square := memoize(func(n int) int {
+			calls++
+			return n * n
+		})
+		[]any{square(3), square(3), square(4), calls}
+		`)
+		if !reflect.DeepEqual(result, []any{9, 9, 16, 2}) {
+			t.Errorf("Expected [9 9 16 2], got %v", result)
+		}
+	})
+
+	// 三段式for循环中每次迭代都有自己的变量，if的初始化语句中的变量不影响外部
+	t.Run("Loop Variables", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		funcs := []any{}
+		for n := 0; n < 3; n++ {
+			funcs = append(funcs, func() int {
+				return n
+			})
+		}
+		values := []any{}
+		for _, f := range funcs {
+			values = append(values, f())
+		}
+		x := 1
+		if x := 2; x > 1 {
+			values = append(values, x)
+		}
+		append(values, x)
+		`)
+		if !reflect.DeepEqual(result, []any{0, 1, 2, 2, 1}) {
+			t.Errorf("Expected [0 1 2 2 1], got %v", result)
+		}
+	})
+
+	// 闭包作为有类型的回调传给宿主函数，定义闭包的代码块结束后仍然可以调用
+	t.Run("Host Callbacks", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		list := []any{3, 1, 2}
+		sortSlice(list, func(a, b int) bool {
+			return list[a] < list[b]
+		})
+		{
+			prefix := "hello "
+			register(func(s string) string {
+				return prefix + s
+			})
+		}
+		seen := []any{}
+		err := each([]string{"a", "b", "c"}, func(idx int, item string) error {
+			if item == "c" {
+				return newError("stop at " + idx)
+			}
+			seen = append(seen, item)
+			return nil
+		})
+		[]any{list, seen, err.Error()}
+		`)
+		expected := []any{[]any{1, 2, 3}, []any{"a", "b"}, "stop at 2"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+		if saved == nil || saved("world") != "hello world" {
+			t.Errorf("Unexpected saved callback")
+		}
+	})
+
+	// 回调中的panic从宿主函数的调用处返回
+	t.Run("Callback Panic", func(t *testing.T) {
+		_, err := newTestInterpreter(bindings).Interpret(`
+		sortSlice([]any{1, 2}, func(a, b int) bool {
+			panic("bad compare")
+		})
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) || p.Value != "bad compare" {
+			t.Fatalf("Expected bad compare panic, got %v", err)
+		}
+	})
+
+	// 先声明函数类型的变量再赋值，闭包可以递归调用自己
+	t.Run("Recursive Closure", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		var fib func(int) int
+		isNil := fib == nil
+		fib = func(n int) int {
+			if n < 2 {
+				return n
+			}
+			return fib(n-1) + fib(n-2)
+		}
+		var join func(sep string, parts ...string) string
+		[]any{isNil, fib(10), join == nil}
+		`)
+		expected := []any{true, 55, true}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+}
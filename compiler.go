@@ -0,0 +1,796 @@
+package goscript
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+// Backend 脚本的执行方式
+type Backend int
+
+const (
+	// BackendTree 直接遍历AST执行，默认的执行方式
+	BackendTree Backend = iota
+	// BackendClosure 执行前将AST编译为闭包树：字面量预先求值，运算符预先绑定到对应的运算函数，变量在编译时解析为作用域中的位置，
+	// 不需要每次都经过 eval 的类型分支；不支持编译的语句和表达式仍然遍历AST执行，结果与 BackendTree 相同
+	BackendClosure
+	// BackendBytecode 执行前将AST编译为字节码，在栈式虚拟机中执行，循环和分支编译为跳转指令；
+	// 不支持编译的语句和表达式通过 EVAL、EXEC 指令遍历AST执行，结果与 BackendTree 相同
+	BackendBytecode
+)
+
+// SetBackend 设置执行脚本的方式，Fork 出的解释器继承该设置
+func (i *Interpreter) SetBackend(backend Backend) {
+	i.backend = backend
+}
+
+// evalFunc 编译后的语句或表达式
+type evalFunc func(i *Interpreter) (any, error)
+
+// closureProgram 编译为闭包的程序
+type closureProgram struct {
+	// 编译过的语句，执行时 eval 遇到这些语句直接调用编译后的闭包
+	code map[ast.Node]evalFunc
+	// 函数体的变量布局，调用函数时创建的作用域按布局在 slots 中保存变量
+	layouts map[*ast.BlockStmt]*scopeLayout
+}
+
+// closureCompiler 将AST编译为闭包树
+type closureCompiler struct {
+	stmts   map[ast.Node]evalFunc
+	layouts map[*ast.BlockStmt]*scopeLayout
+	// 正在编译的语句执行时所在的作用域，nil 表示编译时无法确定（如遍历AST执行的语句创建的作用域）
+	scope *staticScope
+}
+
+// staticScope 编译时确定的作用域，与执行时的 Scope 一一对应
+type staticScope struct {
+	layout *scopeLayout
+	parent *staticScope
+}
+
+// 编译程序中所有函数体（包括函数字面量）中的语句
+// 函数体、代码块以及 if、for 语句的作用域中声明的变量在编译时确定位置，变量的读写直接访问作用域的 slots
+func compileClosures(prog *Program) *closureProgram {
+	c := &closureCompiler{
+		stmts:   make(map[ast.Node]evalFunc),
+		layouts: make(map[*ast.BlockStmt]*scopeLayout),
+	}
+	c.funcBody(nil, prog.main.Type, prog.main.Body)
+	// 函数和方法在脚本主体的作用域中定义，泛型函数在类型参数的作用域中执行
+	main := &staticScope{layout: c.layouts[prog.main.Body]}
+	for _, decl := range prog.funcs {
+		c.scope = main
+		if decl.Type.TypeParams != nil {
+			c.scope = nil
+		}
+		c.funcBody(decl.Recv, decl.Type, decl.Body)
+	}
+	return &closureProgram{code: c.stmts, layouts: c.layouts}
+}
+
+// 编译函数体，参数、命名返回值和函数体中直接声明的变量保存在调用函数时创建的作用域的 slots 中
+func (c *closureCompiler) funcBody(recv *ast.FieldList, fnType *ast.FuncType, body *ast.BlockStmt) {
+	if _, ok := c.layouts[body]; ok {
+		return
+	}
+	layout := newLayout()
+	for _, fields := range []*ast.FieldList{recv, fnType.Params, fnType.Results} {
+		if fields == nil {
+			continue
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				layout.add(name.Name)
+			}
+		}
+	}
+	layout.declare(body.List...)
+	c.layouts[body] = layout
+	outer := c.scope
+	c.scope = &staticScope{layout: layout, parent: outer}
+	for _, stmt := range body.List {
+		c.stmt(stmt)
+	}
+	c.scope = outer
+}
+
+// 进入新的作用域，stmts 是在该作用域中直接执行的语句
+func (c *closureCompiler) push(stmts ...ast.Stmt) *scopeLayout {
+	layout := newLayout()
+	layout.declare(stmts...)
+	c.scope = &staticScope{layout: layout, parent: c.scope}
+	return layout
+}
+
+func (c *closureCompiler) pop() {
+	c.scope = c.scope.parent
+}
+
+// 语句在当前作用域中声明的名字：短变量声明、var、const 和 type 声明
+func (l *scopeLayout) declare(stmts ...ast.Stmt) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.AssignStmt:
+			if s.Tok != token.DEFINE {
+				continue
+			}
+			for _, lhs := range s.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					l.add(ident.Name)
+				}
+			}
+		case *ast.DeclStmt:
+			decl, ok := s.Decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						l.add(name.Name)
+						if decl.Tok == token.CONST {
+							l.consts[name.Name] = true
+						}
+					}
+				case *ast.TypeSpec:
+					l.add(spec.Name.Name)
+				}
+			}
+		case *ast.LabeledStmt:
+			l.declare(s.Stmt)
+		}
+	}
+}
+
+// 不支持编译的节点，执行时遍历AST，其中的语句仍然会通过 eval 使用编译后的闭包
+func walkNode(node ast.Node) evalFunc {
+	return func(i *Interpreter) (any, error) {
+		return i.walk(node)
+	}
+}
+
+// 遍历AST执行的节点，其中通过 eval 执行的语句在编译时无法确定所在的作用域；
+// 函数字面量在节点所在的作用域中求值
+func (c *closureCompiler) walk(node ast.Node) evalFunc {
+	outer := c.scope
+	walkedNodes(node, func(stmt ast.Stmt) {
+		c.scope = nil
+		c.stmt(stmt)
+		c.scope = outer
+	}, func(lit *ast.FuncLit) {
+		c.funcBody(nil, lit.Type, lit.Body)
+	})
+	return walkNode(node)
+}
+
+// 遍历AST执行 node 时通过 eval 执行的语句（不包括其中嵌套的语句）以及 node 中的函数字面量；
+// 遍历AST时直接处理的语句（节点本身、switch 和 select 的 case、带标签的 switch 等）不通过 eval 执行，继续查找其中的语句
+func walkedNodes(node ast.Node, stmt func(ast.Stmt), funcLit func(*ast.FuncLit)) {
+	direct := map[ast.Node]bool{node: true}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.FuncLit:
+			funcLit(s)
+			return false
+		case *ast.CaseClause:
+			return true
+		case *ast.CommClause:
+			if s.Comm != nil {
+				direct[s.Comm] = true
+			}
+			return true
+		}
+		st, ok := n.(ast.Stmt)
+		if !ok {
+			return true
+		}
+		if !direct[st] {
+			stmt(st)
+			return false
+		}
+		switch s := st.(type) {
+		case *ast.LabeledStmt:
+			switch s.Stmt.(type) {
+			case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+				direct[s.Stmt] = true
+			}
+		case *ast.SwitchStmt:
+			direct[s.Body] = true
+		case *ast.TypeSwitchStmt:
+			direct[s.Assign] = true
+			direct[s.Body] = true
+		case *ast.SelectStmt:
+			direct[s.Body] = true
+		}
+		return true
+	})
+}
+
+// 编译语句，每个语句只编译一次
+func (c *closureCompiler) stmt(stmt ast.Stmt) evalFunc {
+	if fn, ok := c.stmts[stmt]; ok {
+		return fn
+	}
+	fn := c.compileStmt(stmt)
+	c.stmts[stmt] = fn
+	return fn
+}
+
+func (c *closureCompiler) compileStmt(stmt ast.Stmt) evalFunc {
+	switch s := stmt.(type) {
+	case *ast.ExprStmt:
+		return c.expr(s.X)
+	case *ast.BlockStmt:
+		layout := c.push(s.List...)
+		list := c.stmtList(s.List)
+		c.pop()
+		return func(i *Interpreter) (any, error) {
+			return list(i.withScope(newScope(i.scope, layout)))
+		}
+	case *ast.AssignStmt:
+		if fn := c.assignStmt(s); fn != nil {
+			return fn
+		}
+	case *ast.IncDecStmt:
+		op := token.ADD
+		if s.Tok == token.DEC {
+			op = token.SUB
+		}
+		return c.compoundAssign(s.X, op, incDecOperand)
+	case *ast.IfStmt:
+		return c.ifStmt(s)
+	case *ast.ForStmt:
+		return c.forStmt(s)
+	case *ast.ReturnStmt:
+		results := c.exprs(s.Results)
+		return func(i *Interpreter) (any, error) {
+			values := make(returnValues, len(results))
+			for idx, result := range results {
+				value, err := result(i)
+				if err != nil {
+					return nil, err
+				}
+				values[idx] = value
+			}
+			return values, nil
+		}
+	}
+	return c.walk(stmt)
+}
+
+// 与 evalStmtList 相同，依次执行语句并处理控制流信号
+func (c *closureCompiler) stmtList(list []ast.Stmt) evalFunc {
+	stmts := make([]evalFunc, len(list))
+	for idx, stmt := range list {
+		stmts[idx] = c.stmt(stmt)
+	}
+	return func(i *Interpreter) (result any, err error) {
+		idx := 0
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = nil, i.runtimePanic(r, list[idx])
+			}
+		}()
+		for ; idx < len(stmts); idx++ {
+			result, err := stmts[idx](i)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := result.(controlFlow); ok {
+				if g, ok := result.(gotoSentinel); ok {
+					if target := labelIndex(list, g.label); target >= 0 {
+						idx = target - 1
+						continue
+					}
+				}
+				return result, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// 编译赋值语句，只处理左右两侧数量相同的赋值和复合赋值，其它形式返回 nil
+func (c *closureCompiler) assignStmt(s *ast.AssignStmt) evalFunc {
+	switch s.Tok {
+	case token.DEFINE, token.ASSIGN:
+		if len(s.Lhs) != len(s.Rhs) {
+			return nil
+		}
+		rhs := c.exprs(s.Rhs)
+		targets := make([]func(i *Interpreter, value any) error, len(s.Lhs))
+		for idx, lhs := range s.Lhs {
+			targets[idx] = c.target(lhs, s.Tok == token.DEFINE)
+		}
+		return func(i *Interpreter) (any, error) {
+			values := make([]any, len(rhs))
+			for idx, expr := range rhs {
+				value, err := expr(i)
+				if err != nil {
+					return nil, err
+				}
+				values[idx] = value
+			}
+			values, err := expandValues(values, len(s.Lhs))
+			if err != nil {
+				return nil, err
+			}
+			for idx, target := range targets {
+				if err := target(i, values[idx]); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		}
+	}
+	op, ok := compoundOps[s.Tok]
+	if !ok || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+		return nil
+	}
+	return c.compoundAssign(s.Lhs[0], op, s.Rhs[0])
+}
+
+// 与 compoundAssign 相同，先计算右侧的值，再读取左值的当前值
+func (c *closureCompiler) compoundAssign(lhs ast.Expr, op token.Token, rhs ast.Expr) evalFunc {
+	operand := c.value(rhs)
+	apply := binaryFunc(op, lhs, rhs)
+	if ident, ok := lhs.(*ast.Ident); !ok || ident.Name == "_" {
+		// 索引表达式等左值由 update 读取和写回，其中的容器和索引只计算一次
+		return func(i *Interpreter) (any, error) {
+			y, err := operand(i)
+			if err != nil {
+				return nil, err
+			}
+			return nil, i.update(lhs, func(x any) (any, error) {
+				if x == nil && isNumber(y) {
+					x = reflect.Zero(reflect.TypeOf(y)).Interface()
+				}
+				return apply(i, x, y)
+			})
+		}
+	}
+	current := c.expr(lhs)
+	target := c.target(lhs, false)
+	return func(i *Interpreter) (any, error) {
+		y, err := operand(i)
+		if err != nil {
+			return nil, err
+		}
+		x, err := current(i)
+		if err != nil {
+			return nil, err
+		}
+		if x == nil && isNumber(y) {
+			x = reflect.Zero(reflect.TypeOf(y)).Interface()
+		}
+		value, err := apply(i, x, y)
+		if err != nil {
+			return nil, err
+		}
+		return nil, target(i, value)
+	}
+}
+
+// 编译赋值的目标，变量在编译时解析，其它目标与 assign 相同
+func (c *closureCompiler) target(lhs ast.Expr, define bool) func(i *Interpreter, value any) error {
+	if ident, ok := lhs.(*ast.Ident); ok && ident.Name != "_" {
+		ref := c.ref(ident)
+		if define {
+			return ref.define
+		}
+		return ref.store
+	}
+	return func(i *Interpreter, value any) error {
+		return i.assign(lhs, value, define)
+	}
+}
+
+// 与 evalIfStmt 相同
+func (c *closureCompiler) ifStmt(s *ast.IfStmt) evalFunc {
+	var init, els evalFunc
+	var layout *scopeLayout
+	if s.Init != nil {
+		layout = c.push(s.Init)
+		defer c.pop()
+		init = c.stmt(s.Init)
+	}
+	if s.Else != nil {
+		els = c.stmt(s.Else)
+	}
+	cond := c.expr(s.Cond)
+	body := c.stmt(s.Body)
+	return func(i *Interpreter) (any, error) {
+		if init != nil {
+			i = i.withScope(newScope(i.scope, layout))
+			if _, err := init(i); err != nil {
+				return nil, err
+			}
+		}
+		value, err := cond(i)
+		if err != nil {
+			return nil, err
+		}
+		if toBool(value) {
+			return body(i)
+		} else if els != nil {
+			return els(i)
+		}
+		return nil, nil
+	}
+}
+
+// 与 evalForStmt 相同，带标签的循环由 evalLabeledStmt 处理
+func (c *closureCompiler) forStmt(f *ast.ForStmt) evalFunc {
+	var init, cond, post evalFunc
+	layout := c.push(f.Init)
+	defer c.pop()
+	// 每次迭代复制的循环变量在 slots 中的位置
+	var slots []int
+	if f.Init != nil {
+		init = c.stmt(f.Init)
+		if assign, ok := f.Init.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+			for _, lhs := range assign.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name != "_" {
+					slots = append(slots, layout.index[ident.Name])
+				}
+			}
+		}
+	}
+	if f.Cond != nil {
+		cond = c.expr(f.Cond)
+	}
+	if f.Post != nil {
+		post = c.stmt(f.Post)
+	}
+	body := c.stmt(f.Body)
+	return func(i *Interpreter) (any, error) {
+		i = i.withScope(newScope(i.scope, layout))
+		if init != nil {
+			if _, err := init(i); err != nil {
+				return nil, err
+			}
+		}
+		for {
+			if cond != nil {
+				value, err := cond(i)
+				if err != nil {
+					return nil, err
+				}
+				if !toBool(value) {
+					break
+				}
+			}
+			result, err := body(i)
+			if err != nil {
+				return nil, err
+			}
+			if exit, signal := loopControl(result, ""); exit {
+				return signal, nil
+			}
+			if len(slots) > 0 {
+				next := newScope(i.scope.parent, layout)
+				for _, idx := range slots {
+					value, _ := i.scope.slot(idx)
+					next.setSlot(idx, unbox(value))
+				}
+				i = i.withScope(next)
+			}
+			if post != nil {
+				if _, err := post(i); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return nil, nil
+	}
+}
+
+func (c *closureCompiler) exprs(exprs []ast.Expr) []evalFunc {
+	fns := make([]evalFunc, len(exprs))
+	for idx, expr := range exprs {
+		fns[idx] = c.expr(expr)
+	}
+	return fns
+}
+
+// 单值上下文中的表达式，多返回值与 expandValues 一样报错
+func (c *closureCompiler) value(expr ast.Expr) evalFunc {
+	fn := c.expr(expr)
+	return func(i *Interpreter) (any, error) {
+		value, err := fn(i)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := value.(tuple); ok {
+			_, err := expandValues([]any{value}, 1)
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+func (c *closureCompiler) expr(expr ast.Expr) evalFunc {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		// 字面量在编译时求值
+		value, err := literalValue(e)
+		return func(*Interpreter) (any, error) {
+			return value, err
+		}
+	case *ast.Ident:
+		return c.ident(e)
+	case *ast.ParenExpr:
+		return c.expr(e.X)
+	case *ast.BinaryExpr:
+		x, y := c.expr(e.X), c.expr(e.Y)
+		apply := binaryFunc(e.Op, e.X, e.Y)
+		return func(i *Interpreter) (any, error) {
+			left, err := x(i)
+			if err != nil {
+				return nil, err
+			}
+			if done, value := shortCircuit(e.Op, left); done {
+				return value, nil
+			}
+			right, err := y(i)
+			if err != nil {
+				return nil, err
+			}
+			return apply(i, left, right)
+		}
+	case *ast.CallExpr:
+		return c.call(e)
+	}
+	return c.walk(expr)
+}
+
+// 编译时就确定的值
+func constant(value any) evalFunc {
+	return func(*Interpreter) (any, error) {
+		return value, nil
+	}
+}
+
+// 与 evalIdent 相同，预定义的标识符在编译时确定
+func (c *closureCompiler) ident(ident *ast.Ident) evalFunc {
+	switch ident.Name {
+	case "true":
+		return constant(true)
+	case "false":
+		return constant(false)
+	case "nil":
+		return constant(nil)
+	case "G":
+		return func(i *Interpreter) (any, error) {
+			return i.global, nil
+		}
+	}
+	return c.ref(ident).load
+}
+
+// slotRef 编译时解析的变量，steps 依次对应编译时确定的各层作用域
+// 变量在某一层的布局中时直接读写该层的 slots；执行时的作用域与编译时不一致时按名字查找
+type slotRef struct {
+	ident *ast.Ident
+	steps []slotStep
+}
+
+type slotStep struct {
+	layout *scopeLayout
+	// 变量在布局中的位置，-1 表示不在布局中
+	index int
+}
+
+// 解析变量，变量在当前作用域中定义时也在当前作用域中查找
+func (c *closureCompiler) ref(ident *ast.Ident) *slotRef {
+	ref := &slotRef{ident: ident}
+	for s := c.scope; s != nil; s = s.parent {
+		ref.steps = append(ref.steps, slotStep{layout: s.layout, index: s.layout.indexOf(ident.Name)})
+	}
+	return ref
+}
+
+// 查找变量所在的作用域，idx >= 0 时变量保存在返回的作用域的 slots 中，
+// 否则变量不在编译时确定的作用域中，需要从返回的作用域开始按名字查找；ok 为 false 表示执行时的作用域与编译时不一致
+func (r *slotRef) find(scope *Scope) (s *Scope, idx int, ok bool) {
+	for _, step := range r.steps {
+		if scope == nil || scope.layout != step.layout {
+			return nil, -1, false
+		}
+		if step.index >= 0 {
+			// 变量可能还没有定义，如 goto 跳过了变量的声明
+			if _, defined := scope.slot(step.index); defined {
+				return scope, step.index, true
+			}
+		} else if scope.hasExtra() {
+			// 作用域中有布局之外的变量（如遍历AST执行的语句声明的变量）
+			return scope, -1, true
+		}
+		scope = scope.parent
+	}
+	return scope, -1, true
+}
+
+// 读取变量的值，与 lookup 相同
+func (r *slotRef) load(i *Interpreter) (any, error) {
+	scope, idx, ok := r.find(i.scope)
+	if !ok {
+		return i.lookup(r.ident)
+	}
+	if idx >= 0 {
+		value, _ := scope.slot(idx)
+		return unbox(value), nil
+	}
+	for ; scope != nil; scope = scope.parent {
+		if value, ok := scope.Load(r.ident.Name); ok {
+			return unbox(value), nil
+		}
+	}
+	// global 对象上的属性和未定义的标识符
+	return i.evalIdent(r.ident)
+}
+
+// 给变量赋值，与 assign 相同
+func (r *slotRef) store(i *Interpreter, value any) error {
+	scope, idx, ok := r.find(i.scope)
+	if !ok {
+		return setVariable(i.scope, r.ident.Name, value)
+	}
+	if idx < 0 || scope.layout.consts[r.ident.Name] {
+		// 常量由 setVariable 返回错误
+		return setVariable(scope, r.ident.Name, value)
+	}
+	current, _ := scope.slot(idx)
+	if box, ok := current.(*varBox); ok {
+		return box.set(value)
+	}
+	scope.setSlot(idx, value)
+	return nil
+}
+
+// 在当前作用域中定义变量，与 assign 相同
+func (r *slotRef) define(i *Interpreter, value any) error {
+	if len(r.steps) == 0 || i.scope.layout != r.steps[0].layout || r.steps[0].index < 0 || r.steps[0].layout.consts[r.ident.Name] {
+		return i.assign(r.ident, value, true)
+	}
+	idx := r.steps[0].index
+	current, _ := i.scope.slot(idx)
+	if box, ok := current.(*varBox); ok {
+		return box.set(value)
+	}
+	i.scope.setSlot(idx, value)
+	return nil
+}
+
+// 查找变量的值，与 evalIdent 相同，但不处理预定义的标识符
+func (i *Interpreter) lookup(ident *ast.Ident) (any, error) {
+	for scope := i.scope; scope != nil; scope = scope.parent {
+		if value, ok := scope.Load(ident.Name); ok {
+			return unbox(value), nil
+		}
+	}
+	// global 对象上的属性和未定义的标识符
+	return i.evalIdent(ident)
+}
+
+// 编译对变量中的函数的调用，内置函数、类型转换、方法调用和展开参数的调用遍历AST执行
+func (c *closureCompiler) call(call *ast.CallExpr) evalFunc {
+	ident, ok := compilableCall(call)
+	if !ok {
+		return c.walk(call)
+	}
+	fun := c.ident(ident)
+	args := c.exprs(call.Args)
+	return func(i *Interpreter) (any, error) {
+		fn, err := fun(i)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := fn.(reflect.Type); ok {
+			// 脚本中定义的类型的转换
+			return i.walk(call)
+		}
+		// 与 evalArgs 相同，唯一的参数是多返回值时展开为多个参数
+		values := make([]any, len(args))
+		for idx, arg := range args {
+			value, err := arg(i)
+			if err != nil {
+				return nil, err
+			}
+			values[idx] = value
+		}
+		if len(values) == 1 {
+			if t, ok := values[0].(tuple); ok {
+				values = t
+			}
+		}
+		return i.callFunction(call, fn, values)
+	}
+}
+
+// 调用的函数是变量（而不是内置函数、基本类型或方法）并且没有展开参数时，调用可以编译
+// 内置函数的名字可能被变量覆盖，只能在执行时判断，所以遍历AST执行
+func compilableCall(call *ast.CallExpr) (*ast.Ident, bool) {
+	ident, ok := call.Fun.(*ast.Ident)
+	if !ok || call.Ellipsis.IsValid() || builtins[ident.Name] || basicTypes[ident.Name] != nil || ident.Name == "any" || ident.Name == "error" {
+		return nil, false
+	}
+	return ident, true
+}
+
+// 编译时根据运算符选择运算函数，两个操作数都是 int、都是 float64 或都是 string 时直接计算，其它情况与 applyBinary 相同
+// x、y 是操作数的表达式，用于判断操作数是否是无类型常量
+func binaryFunc(op token.Token, x, y ast.Expr) func(i *Interpreter, a, b any) (any, error) {
+	generic := func(i *Interpreter, a, b any) (any, error) {
+		return i.applyBinary(op, a, b, x, y)
+	}
+	var ints func(x, y int) (any, bool)
+	var floats func(x, y float64) (any, bool)
+	var strs func(x, y string) (any, bool)
+	switch op {
+	case token.ADD:
+		ints = func(x, y int) (any, bool) { return x + y, true }
+		floats = func(x, y float64) (any, bool) { return x + y, true }
+		strs = func(x, y string) (any, bool) { return x + y, true }
+	case token.SUB:
+		ints = func(x, y int) (any, bool) { return x - y, true }
+		floats = func(x, y float64) (any, bool) { return x - y, true }
+	case token.MUL:
+		ints = func(x, y int) (any, bool) { return x * y, true }
+		floats = func(x, y float64) (any, bool) { return x * y, true }
+	case token.QUO:
+		// 除以零时由 binaryOp 产生运行时panic
+		ints = func(x, y int) (any, bool) {
+			if y == 0 {
+				return nil, false
+			}
+			return x / y, true
+		}
+		floats = func(x, y float64) (any, bool) {
+			if y == 0 {
+				return nil, false
+			}
+			return x / y, true
+		}
+	case token.REM:
+		ints = func(x, y int) (any, bool) {
+			if y == 0 {
+				return nil, false
+			}
+			return x % y, true
+		}
+	case token.LSS, token.GTR, token.LEQ, token.GEQ, token.EQL, token.NEQ:
+		// 与 comparison 相同，NaN 与任何值比较时只有 != 成立
+		ints = func(x, y int) (any, bool) { return ordered(op, x, y), true }
+		floats = func(x, y float64) (any, bool) { return ordered(op, x, y), true }
+		strs = func(x, y string) (any, bool) { return ordered(op, x, y), true }
+	default:
+		return generic
+	}
+	return func(i *Interpreter, a, b any) (any, error) {
+		switch x := a.(type) {
+		case int:
+			if y, ok := b.(int); ok && ints != nil {
+				if value, ok := ints(x, y); ok {
+					return value, nil
+				}
+			}
+		case float64:
+			if y, ok := b.(float64); ok && floats != nil {
+				if value, ok := floats(x, y); ok {
+					return value, nil
+				}
+			}
+		case string:
+			if y, ok := b.(string); ok && strs != nil {
+				if value, ok := strs(x, y); ok {
+					return value, nil
+				}
+			}
+		}
+		return generic(i, a, b)
+	}
+}
@@ -0,0 +1,207 @@
+package goscript
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 同一段脚本使用两种方式执行的结果应该相同
+func TestClosureBackend(t *testing.T) {
+	bindings := map[string]any{
+		"upper": strings.ToUpper,
+	}
+
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"Arithmetic", `
+		x := 7
+		y := 2.5
+		var z int64 = 3
+		return []any{x + 3, x - 10, x * x, x / 2, x % 4, x < 8, x >= 7, x == 7, x != 7, y * 2, z + 1, "a" + "b", "n" + x}`},
+		{"Loop", `
+		sum := 0
+		for i := 0; i < 100; i++ {
+			if i%3 == 0 {
+				continue
+			}
+			sum += i
+		}
+		sum`},
+		{"Recursion", `
+		func fib(n int) int {
+			if n < 2 {
+				return n
+			}
+			return fib(n-1) + fib(n-2)
+		}
+		fib(15)`},
+		{"Closures", `
+		fns := []any{}
+		for i := 0; i < 3; i++ {
+			fns = append(fns, func() int { return i * 10 })
+		}
+		out := []any{}
+		for _, f := range fns {
+			out = append(out, f())
+		}
+		out`},
+		{"Multi Assign", `
+		func divmod(a, b int) (int, int) {
+			return a / b, a % b
+		}
+		a, b := 1, 2
+		a, b = b, a
+		q, r := divmod(17, 5)
+		[]any{a, b, q, r}`},
+		{"Labels And Switch", `
+		count := 0
+	outer:
+		for i := 0; i < 5; i++ {
+			for j := 0; j < 5; j++ {
+				switch {
+				case j > i:
+					continue outer
+				case i == 4:
+					break outer
+				}
+				count++
+			}
+		}
+		count`},
+		{"Host Calls", `
+		s := ""
+		for _, w := range []any{"a", "b"} {
+			s += upper(w)
+		}
+		s`},
+		{"Conversions", `
+		type Celsius float64
+		c := Celsius(36.6)
+		[]any{int(c), float64(c) > 36}`},
+		{"Float And String Ops", `
+		x, y := 1.5, 0.25
+		a, b := "abc", "abd"
+		[]any{x + y, x - y, x * y, x / y, x < y, x >= y, x == 1.5, x != y, a + b, a < b, a >= b, a == "abc", a != b}`},
+		{"Scopes", `
+		x := 1
+		get := func() any { return later }
+		later := "late"
+		n := 0
+		if x := 2; x > 1 {
+			n += x
+			{
+				x := 3
+				n += x
+				x++
+			}
+			n += x
+		}
+		var p *int
+		var e error
+		q := &x
+		*q = 10
+		i := 0
+	loop:
+		if i < 3 {
+			i++
+			goto loop
+		}
+		switch y := x * 2; {
+		case y > 0:
+			n += y
+		}
+		[]any{x, n, get(), p == nil, e == nil, i}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := newBackendInterpreter(bindings, BackendTree).Interpret(tt.code)
+			if err != nil {
+				t.Fatalf("Tree error: %v", err)
+			}
+			got, err := newBackendInterpreter(bindings, BackendClosure).Interpret(tt.code)
+			if err != nil {
+				t.Fatalf("Closure error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+
+	// 错误和panic与遍历AST执行时相同
+	t.Run("Errors", func(t *testing.T) {
+		for _, code := range []string{
+			`x := 1; x / 0`,
+			`func f() (int, int) { return 1, 2 }
+			x := 0
+			x += f()`,
+			`panic("bad")`,
+			`const c = 1
+			func() { c = 2 }()`,
+		} {
+			_, want := newBackendInterpreter(bindings, BackendTree).Interpret(code)
+			_, got := newBackendInterpreter(bindings, BackendClosure).Interpret(code)
+			if want == nil || got == nil || got.Error() != want.Error() {
+				t.Errorf("Expected error %v, got %v", want, got)
+			}
+		}
+	})
+
+	// Fork 出的解释器继承执行方式，同一个程序可以分别用两种方式执行
+	t.Run("Fork", func(t *testing.T) {
+		prog, err := Compile(`n := 0; for i := 1; i <= 10; i++ { n += i }; n`)
+		if err != nil {
+			t.Fatalf("Compile error: %v", err)
+		}
+		fork := newBackendInterpreter(bindings, BackendClosure).Fork()
+		if fork.backend != BackendClosure {
+			t.Fatalf("Expected fork to use the closure backend")
+		}
+		for _, interp := range []*Interpreter{fork, newBackendInterpreter(bindings, BackendTree)} {
+			result, err := interp.Run(prog)
+			if err != nil {
+				t.Fatalf("Error: %v", err)
+			}
+			if result != 55 {
+				t.Errorf("Expected 55, got %v", result)
+			}
+		}
+	})
+}
+
+func benchmarkBackend(b *testing.B, backend Backend) {
+	prog, err := Compile(`
+	func fib(n int) int {
+		if n < 2 {
+			return n
+		}
+		return fib(n-1) + fib(n-2)
+	}
+	sum := 0
+	for i := 0; i < 1000; i++ {
+		sum += i * 2
+	}
+	fib(12) + sum`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	interp := NewInterpreter()
+	interp.SetBackend(backend)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := interp.Fork().Run(prog); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTreeBackend(b *testing.B) {
+	benchmarkBackend(b, BackendTree)
+}
+
+func BenchmarkClosureBackend(b *testing.B) {
+	benchmarkBackend(b, BackendClosure)
+}
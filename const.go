@@ -0,0 +1,98 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+)
+
+// constKey 标记作用域中的常量，与变量保存在同一个作用域中，不会和变量名冲突
+type constKey string
+
+// SetConst 设置常量，脚本中不能给常量赋值
+func (i *Interpreter) SetConst(name string, value any) {
+	i.Set(name, value)
+	i.scope.Store(constKey(name), true)
+}
+
+// 作用域中的变量是否是常量
+func (s *Scope) isConst(name string) bool {
+	_, ok := s.Load(constKey(name))
+	return ok
+}
+
+// 名字是否是没有声明类型的常量，这样的常量与go的无类型常量一样，运算时采用另一个操作数的类型
+// 宿主设置的常量也视为无类型常量
+func (i *Interpreter) isUntypedConst(name string) bool {
+	for currentScope := i.scope; currentScope != nil; currentScope = currentScope.parent {
+		if _, ok := currentScope.Load(name); ok {
+			untyped, _ := currentScope.Load(constKey(name))
+			return untyped == true
+		}
+	}
+	return false
+}
+
+// 处理常量声明，支持 iota 以及在常量组中省略表达式时重复上一个表达式
+//
+//	const (
+//		A = iota * 10
+//		B
+//		C
+//	)
+func (i *Interpreter) evalConstDecl(decl *ast.GenDecl) error {
+	var typeExpr ast.Expr
+	var values []ast.Expr
+	for iota, spec := range decl.Specs {
+		valueSpec := spec.(*ast.ValueSpec)
+		if len(valueSpec.Values) > 0 {
+			typeExpr, values = valueSpec.Type, valueSpec.Values
+		} else if valueSpec.Type != nil || iota == 0 {
+			return fmt.Errorf("常量 %s 缺少初始值", valueSpec.Names[0].Name)
+		}
+		if len(values) != len(valueSpec.Names) {
+			return fmt.Errorf("常量数量不匹配: %d 个常量, 但有 %d 个值", len(valueSpec.Names), len(values))
+		}
+
+		var typ reflect.Type
+		if typeExpr != nil {
+			var err error
+			if typ, err = i.resolveType(typeExpr); err != nil {
+				return err
+			}
+		}
+
+		// 在单独的作用域中计算表达式，其中 iota 为常量在常量组中的序号
+		iotaScope := &Scope{parent: i.scope}
+		iotaScope.Store("iota", iota)
+		results := make([]any, len(values))
+		for idx, expr := range values {
+			value, err := i.withScope(iotaScope).eval(expr)
+			if err != nil {
+				return err
+			}
+			results[idx] = value
+		}
+
+		for idx, name := range valueSpec.Names {
+			value := results[idx]
+			if typ != nil {
+				// 有类型的常量转换为声明的类型
+				converted, err := toValue(value, typ)
+				if err != nil {
+					return fmt.Errorf("常量 %s: %v", name.Name, err)
+				}
+				value = converted.Interface()
+			}
+			if name.Name == "_" {
+				continue
+			}
+			if i.scope.isConst(name.Name) {
+				return fmt.Errorf("常量 %s 重复声明", name.Name)
+			}
+			i.scope.Store(name.Name, value)
+			i.scope.Store(constKey(name.Name), typ == nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package goscript
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConst(t *testing.T) {
+	// iota 以及省略表达式时重复上一个表达式
+	t.Run("Iota", func(t *testing.T) {
+		result, err := NewInterpreter().Interpret(`
+		const (
+			A = iota
+			B
+			_
+			D
+		)
+		const (
+			KB = 1 << (10 * (iota + 1))
+			MB
+			GB
+		)
+		const (
+			X, Y = iota, iota * 10
+			Z, W
+		)
+		[]any{A, B, D, KB, MB, GB, X, Y, Z, W}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		expected := []any{0, 1, 3, 1024, 1 << 20, 1 << 30, 0, 0, 1, 10}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 有类型和无类型的常量
+	t.Run("Typed", func(t *testing.T) {
+		result, err := NewInterpreter().Interpret(`
+		const Pi = 3.14
+		const Max int64 = 100
+		const (
+			Low uint8 = iota + 1
+			High
+		)
+		const Name string = "goscript"
+		[]any{Pi, Max, Low, High, Name}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		expected := []any{3.14, int64(100), uint8(1), uint8(2), "goscript"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 给常量赋值返回错误
+	t.Run("Immutable", func(t *testing.T) {
+		for _, code := range []string{
+			`const A = 1
+			A = 2`,
+			`const A = 1
+			A++`,
+			`const A = 1
+			A += 1`,
+			`const A = 1
+			A := 2`,
+			`const A = 1
+			f := func() {
+				A = 2
+			}
+			f()`,
+		} {
+			if _, err := NewInterpreter().Interpret(code); err == nil {
+				t.Errorf("Expected error for %s", code)
+			}
+		}
+
+		// 内层作用域中可以声明同名的变量
+		result, err := NewInterpreter().Interpret(`
+		const A = 1
+		f := func() {
+			A := 2
+			return A
+		}
+		f() + A
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != 3 {
+			t.Errorf("Expected 3, got %v", result)
+		}
+	})
+
+	// 宿主设置的常量
+	t.Run("SetConst", func(t *testing.T) {
+		interp := NewInterpreter()
+		interp.SetConst("Version", "1.0")
+		result, err := interp.Interpret(`"v" + Version`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != "v1.0" {
+			t.Errorf("Expected v1.0, got %v", result)
+		}
+		if _, err := interp.Interpret(`Version = "2.0"`); err == nil {
+			t.Errorf("Expected error")
+		}
+		if interp.Get("Version") != "1.0" {
+			t.Errorf("Expected constant to be unchanged, got %v", interp.Get("Version"))
+		}
+	})
+}
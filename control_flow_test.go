@@ -0,0 +1,298 @@
+package goscript
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestControlFlow(t *testing.T) {
+	// 嵌套在 if 和循环中的 return 立即退出函数
+	t.Run("Early Return", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		find := func(list []any, target int) int {
+			for i, v := range list {
+				if v == target {
+					return i
+				}
+			}
+			return -1
+		}
+		sign := func(n int) string {
+			if n < 0 {
+				return "negative"
+			}
+			if n == 0 {
+				return "zero"
+			}
+			return "positive"
+		}
+		[]any{find([]any{5, 6, 7}, 6), find([]any{5}, 1), sign(-2), sign(0), sign(3)}
+		`)
+		expected := []any{1, -1, "negative", "zero", "positive"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// return 之后的语句不会执行
+	t.Run("Statements After Return", func(t *testing.T) {
+		var logs []any
+		interp := NewInterpreter()
+		interp.Set("record", func(v any) {
+			logs = append(logs, v)
+		})
+		_, err := interp.Interpret(`
+		f := func() {
+			for {
+				if true {
+					record(1)
+					return
+				}
+			}
+			record(2)
+		}
+		f()
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(logs, []any{1}) {
+			t.Errorf("Expected [1], got %v", logs)
+		}
+	})
+
+	// if 和 switch 中的 break、continue 作用于外层循环
+	t.Run("Break And Continue In Nested Blocks", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		sum := 0
+		for i := 0; i < 10; i++ {
+			if i % 2 == 0 {
+				continue
+			}
+			if i > 7 {
+				break
+			}
+			switch i {
+			case 5:
+				continue
+			}
+			sum += i
+		}
+		sum
+		`)
+		// 1 + 3 + 7
+		if result != 11 {
+			t.Errorf("Expected 11, got %v", result)
+		}
+	})
+
+	// switch 中的 break 只退出 switch
+	t.Run("Break In Switch", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		count := 0
+		for _, v := range []any{1, 2, 3} {
+			switch v {
+			case 2:
+				break
+				count += 100
+			}
+			count++
+		}
+		count
+		`)
+		if result != 3 {
+			t.Errorf("Expected 3, got %v", result)
+		}
+	})
+
+	// 循环中的 return 带出返回值
+	t.Run("Return From Loop", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		f := func() (int, string) {
+			for i := 0; ; i++ {
+				if i == 3 {
+					return i, "done"
+				}
+			}
+		}
+		n, s := f()
+		[]any{n, s}
+		`)
+		if !reflect.DeepEqual(result, []any{3, "done"}) {
+			t.Errorf("Expected [3 done], got %v", result)
+		}
+	})
+
+	// 语句的值不会作为函数或脚本的结果
+	t.Run("No Leaking Values", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		f := func() {
+			x := 5
+			x + 1
+		}
+		[]any{f()}
+		`)
+		if !reflect.DeepEqual(result, []any{nil}) {
+			t.Errorf("Expected [nil], got %v", result)
+		}
+
+		result = runScript(t, NewInterpreter(), `
+		x := 1
+		if x > 0 {
+			x + 10
+		}
+		`)
+		if result != nil {
+			t.Errorf("Expected nil, got %v", result)
+		}
+
+		result = runScript(t, NewInterpreter(), `
+		x := 1
+		x = 2
+		`)
+		if result != nil {
+			t.Errorf("Expected nil, got %v", result)
+		}
+	})
+
+	// 循环外的 break 返回错误
+	t.Run("Break Outside Loop", func(t *testing.T) {
+		if _, err := NewInterpreter().Interpret(`
+		f := func() {
+			break
+		}
+		f()
+		`); err == nil {
+			t.Errorf("Expected error")
+		}
+	})
+}
+
+func TestBranchStatements(t *testing.T) {
+	// 带标签的 continue 和 break 作用于外层循环
+	t.Run("Labeled Loops", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		pairs := []any{}
+	outer:
+		for i := 0; i < 4; i++ {
+			for _, j := range []any{0, 1, 2, 3} {
+				if j > i {
+					continue outer
+				}
+				if i == 3 {
+					break outer
+				}
+				pairs = append(pairs, i * 10 + j)
+			}
+		}
+		pairs
+		`)
+		expected := []any{0, 10, 11, 20, 21, 22}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 在 switch 中用标签退出外层循环
+	t.Run("Break Loop From Switch", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		n := 0
+	loop:
+		for {
+			n++
+			switch {
+			case n < 3:
+				continue
+			default:
+				break loop
+			}
+		}
+		n
+		`)
+		if result != 3 {
+			t.Errorf("Expected 3, got %v", result)
+		}
+	})
+
+	// goto 可以向前和向后跳转，也可以跳出嵌套的块
+	t.Run("Goto", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		f := func() []any {
+			out := []any{}
+			i := 0
+		again:
+			if i < 3 {
+				out = append(out, i)
+				i++
+				goto again
+			}
+			for {
+				if i > 0 {
+					goto done
+				}
+			}
+			out = append(out, "skipped")
+		done:
+			return append(out, "done")
+		}
+		f()
+		`)
+		expected := []any{0, 1, 2, "done"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// fallthrough 继续执行下一个 case，包括 default
+	t.Run("Fallthrough", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		f := func(n int) []any {
+			out := []any{}
+			switch n {
+			case 1:
+				out = append(out, "one")
+				fallthrough
+			case 2:
+				out = append(out, "two")
+				fallthrough
+			default:
+				out = append(out, "default")
+			case 3:
+				out = append(out, "three")
+			}
+			return out
+		}
+		[]any{f(1), f(2), f(3), f(4)}
+		`)
+		expected := []any{
+			[]any{"one", "two", "default"},
+			[]any{"two", "default"},
+			[]any{"three"},
+			[]any{"default"},
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 错误的用法
+	t.Run("Errors", func(t *testing.T) {
+		for _, code := range []string{
+			`goto missing`,
+			`switch 1 {
+			case 1:
+				fallthrough
+			}`,
+			`var x any = 1
+			switch x.(type) {
+			case int:
+				fallthrough
+			default:
+			}`,
+		} {
+			if _, err := NewInterpreter().Interpret(code); err == nil {
+				t.Errorf("Expected error for %s", code)
+			}
+		}
+	})
+}
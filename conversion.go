@@ -0,0 +1,215 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"math"
+	"reflect"
+)
+
+// conversionType 判断调用表达式的函数部分是否是类型，如 int(x)、[]byte(s)、time.Duration(n)、(*T)(p)
+func (i *Interpreter) conversionType(fun ast.Expr) (reflect.Type, bool) {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		if _, ok := basicTypes[f.Name]; !ok && f.Name != "any" && f.Name != "error" {
+			// 脚本中定义的类型和泛型函数的类型参数
+			value, _ := i.load(f.Name)
+			if _, ok := value.(reflect.Type); !ok {
+				return nil, false
+			}
+		}
+	case *ast.SelectorExpr:
+		// 包中的类型，如 time.Duration
+		x, ok := f.X.(*ast.Ident)
+		if !ok {
+			return nil, false
+		}
+		value, _ := i.load(x.Name)
+		pkg, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if _, ok := pkg[f.Sel.Name].(reflect.Type); !ok {
+			return nil, false
+		}
+	case *ast.ParenExpr:
+		if star, ok := f.X.(*ast.StarExpr); ok {
+			// (*T)(p)
+			typ, ok := i.conversionType(star.X)
+			if !ok {
+				return nil, false
+			}
+			return reflect.PtrTo(typ), true
+		}
+		return i.conversionType(f.X)
+	case *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType:
+	default:
+		return nil, false
+	}
+	typ, err := i.resolveType(fun)
+	if err != nil {
+		return nil, false
+	}
+	return typ, true
+}
+
+// 处理类型转换表达式 T(x)
+func (i *Interpreter) evalConversion(call *ast.CallExpr, typ reflect.Type) (any, error) {
+	if len(call.Args) != 1 || call.Ellipsis.IsValid() {
+		return nil, fmt.Errorf("类型转换 %s 需要一个参数", typeName(typ))
+	}
+	value, err := i.eval(call.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	if i.isUntyped(call.Args[0]) && exactConstant(call.Args[0]) {
+		// 与go一样，无类型常量必须可以用目标类型表示，变量的转换才按位数回绕
+		if err := representable(value, typ); err != nil {
+			return nil, err
+		}
+	}
+	if items, ok := value.([]any); ok && typ.Kind() == reflect.String {
+		if value, err = i.textSlice(call.Args[0], items); err != nil {
+			return nil, err
+		}
+	}
+	converted, err := convert(value, typ)
+	if p, ok := err.(*PanicError); ok {
+		p.Pos = i.position(call.Pos())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return converted.Interface(), nil
+}
+
+// convert 按go的规则将值转换为指定类型：数值类型之间、整数到字符串、字符串和 []byte/[]rune 之间、
+// 底层类型相同的类型之间都可以转换；脚本中的 []any 和 map 按 toValue 的规则逐个元素转换
+func convert(value any, typ reflect.Type) (v reflect.Value, err error) {
+	if value == nil {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func, reflect.Interface:
+			return reflect.Zero(typ), nil
+		}
+		return reflect.Value{}, fmt.Errorf("无法将 nil 转换为 %s", typeName(typ))
+	}
+
+	src := reflect.ValueOf(value)
+	if typ.Kind() == reflect.Interface {
+		if !src.Type().Implements(typ) {
+			return reflect.Value{}, fmt.Errorf("无法将 %s 转换为 %s: 没有实现该接口", typeName(src.Type()), typeName(typ))
+		}
+		// 接口类型的值保留原来的动态类型
+		out := reflect.New(typ).Elem()
+		out.Set(src)
+		return out, nil
+	}
+	if src.Type().ConvertibleTo(typ) {
+		// 切片转换为长度不足的数组等情况会panic
+		defer func() {
+			if r := recover(); r != nil {
+				v, err = reflect.Value{}, &PanicError{Value: fmt.Sprint(r)}
+			}
+		}()
+		return src.Convert(typ), nil
+	}
+	if src.Kind() == reflect.Slice || src.Kind() == reflect.Map || src.Kind() == reflect.Array {
+		if converted, err := toValue(value, typ); err == nil {
+			return converted, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("无法将 %s 转换为 %s", typeName(src.Type()), typeName(typ))
+}
+
+// 常量表达式的值是否是精确的：字面量（可以带正负号）和常量名
+// 常量之间的运算按 int 计算，结果可能已经回绕（如 1 << 63），不检查是否可以用目标类型表示
+func exactConstant(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit, *ast.Ident:
+		return true
+	case *ast.ParenExpr:
+		return exactConstant(e.X)
+	case *ast.UnaryExpr:
+		return exactConstant(e.X)
+	}
+	return false
+}
+
+// 无类型常量 value 是否可以用数值类型 typ 表示，如 uint8(300)、uint(-1) 溢出，int(2.5) 被截断
+func representable(value any, typ reflect.Type) error {
+	if !isNumber(value) || !isNumberKind(typ.Kind()) {
+		return nil
+	}
+	v, target := reflect.ValueOf(value), reflect.Zero(typ)
+	overflow := false
+	switch {
+	case isFloatKind(typ.Kind()):
+		var f float64
+		switch {
+		case isIntKind(v.Kind()):
+			f = float64(v.Int())
+		case isUintKind(v.Kind()):
+			f = float64(v.Uint())
+		default:
+			f = v.Float()
+		}
+		overflow = target.OverflowFloat(f)
+	case isFloatKind(v.Kind()):
+		f := v.Float()
+		if f != math.Trunc(f) {
+			return fmt.Errorf("常量 %v 转换为 %s 时被截断", value, typeName(typ))
+		}
+		if isIntKind(typ.Kind()) {
+			overflow = f < math.MinInt64 || f >= math.MaxInt64 || target.OverflowInt(int64(f))
+		} else {
+			overflow = f < 0 || f >= math.MaxUint64 || target.OverflowUint(uint64(f))
+		}
+	case isIntKind(v.Kind()):
+		n := v.Int()
+		if isIntKind(typ.Kind()) {
+			overflow = target.OverflowInt(n)
+		} else {
+			overflow = n < 0 || target.OverflowUint(uint64(n))
+		}
+	default:
+		n := v.Uint()
+		if isIntKind(typ.Kind()) {
+			overflow = n > math.MaxInt64 || target.OverflowInt(int64(n))
+		} else {
+			overflow = target.OverflowUint(n)
+		}
+	}
+	if overflow {
+		return fmt.Errorf("常量 %v 超出 %s 的范围", value, typeName(typ))
+	}
+	return nil
+}
+
+// 脚本中的切片 []any 转换为字符串前先转换为 []byte 或 []rune
+// 元素类型由切片字面量的类型（如 []byte{104, 105}）决定；无法确定时，元素都是 byte 的切片按 []byte 转换，否则按 []rune 转换
+func (i *Interpreter) textSlice(expr ast.Expr, items []any) (any, error) {
+	var elem reflect.Type
+	if lit, ok := expr.(*ast.CompositeLit); ok {
+		if array, ok := lit.Type.(*ast.ArrayType); ok {
+			elem, _ = i.resolveType(array.Elt)
+		}
+	}
+	if elem == nil {
+		elem = basicTypes["byte"]
+		for _, item := range items {
+			if _, ok := item.(byte); !ok {
+				elem = basicTypes["rune"]
+				break
+			}
+		}
+	}
+	target := reflect.TypeOf([]rune(nil))
+	if elem.Kind() == reflect.Uint8 {
+		target = reflect.TypeOf([]byte(nil))
+	}
+	converted, err := toValue(items, target)
+	if err != nil {
+		return nil, err
+	}
+	return converted.Interface(), nil
+}
@@ -0,0 +1,142 @@
+package goscript
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConversion(t *testing.T) {
+	bindings := map[string]any{
+		"time": map[string]any{
+			"Duration": reflect.TypeOf(time.Duration(0)),
+			"Second":   time.Second,
+		},
+		"data": []byte("hi"),
+	}
+	// 数值类型之间的转换
+	t.Run("Numbers", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		x := 3.9
+		n := 300
+		[]any{int(x), float64(2), int64(n), uint8(n), float32(1.5), int(-x), byte('a')}
+		`)
+		expected := []any{3, 2.0, int64(300), uint8(44), float32(1.5), -3, byte('a')}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 字符串与 []byte、[]rune 和整数之间的转换
+	t.Run("Strings", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		b := []byte("abc")
+		r := []rune("中文")
+		[]any{b, r, string(b), string(r), string(data), string(65), string('中'), len([]byte("中"))}
+		`)
+		expected := []any{[]byte("abc"), []rune("中文"), "abc", "中文", "hi", "A", "中", 3}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 脚本中的 []byte、[]rune 切片字面量转换为字符串
+	t.Run("Slices To String", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		bs := []byte{104, 105}
+		rs := []rune{20013, 25991}
+		[]any{string([]byte{104, 105}), string([]byte{0xe4, 0xb8, 0xad}), string([]rune{20013, 25991}), string(bs), string(rs)}
+		`)
+		expected := []any{"hi", "中", "中文", "hi", "中文"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 常量转换时溢出或被截断是错误，变量的转换按位数回绕
+	t.Run("Constant Overflow", func(t *testing.T) {
+		for _, code := range []string{
+			`uint8(300)`,
+			`uint(-1)`,
+			`int8(-129)`,
+			`int(2.5)`,
+			`const big = 256
+			byte(big)`,
+			`float32(1e40)`,
+		} {
+			if _, err := newTestInterpreter(bindings).Interpret(code); err == nil {
+				t.Errorf("Expected error for %s", code)
+			}
+		}
+		result := runScript(t, newTestInterpreter(bindings), `
+		n := 300
+		[]any{uint8(255), uint8(n), int8(-128), int(2.0), uint64(1 << 63)}
+		`)
+		expected := []any{uint8(255), uint8(44), int8(-128), 2, uint64(1 << 63)}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 包中的类型、脚本中的类型和接口类型
+	t.Run("Named Types", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		type Celsius float64
+		type Point struct {
+			X int
+		}
+		type Pair struct {
+			X int
+		}
+		d := time.Duration(2) * time.Second
+		p := Pair(Point{X: 1})
+		ptr := (*Point)(nil)
+		[]any{d, Celsius(36), p.X, any(1), ptr == nil, []int([]any{1, 2})}
+		`)
+		expected := []any{2 * time.Second, 36.0, 1, 1, true, []int{1, 2}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 泛型函数中通过类型参数转换
+	t.Run("Type Parameters", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		func To[T int | float64 | string](x any) T {
+			return T(x)
+		}
+		[]any{To[int](2.5), To[float64](2), To[string](66)}
+		`)
+		expected := []any{2, 2.0, "B"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 不合法的转换
+	t.Run("Invalid", func(t *testing.T) {
+		for _, code := range []string{
+			`string(1.5)`,
+			`int("1")`,
+			`bool(1)`,
+			`int(nil)`,
+			`error(1)`,
+			`int(1, 2)`,
+		} {
+			if _, err := newTestInterpreter(bindings).Interpret(code); err == nil {
+				t.Errorf("Expected error for %s", code)
+			}
+		}
+
+		_, err := newTestInterpreter(bindings).Interpret(`
+		s := []int([]any{1})
+		a := [2]int(s)
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) || p.Pos.Line != 3 || !strings.Contains(p.Error(), "length") {
+			t.Errorf("Expected panic at line 3, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package goscript
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDefer(t *testing.T) {
+	var logs []any
+	bindings := map[string]any{
+		"record": func(v any) {
+			logs = append(logs, v)
+		},
+	}
+
+	// 测试后进先出的执行顺序
+	t.Run("LIFO", func(t *testing.T) {
+		logs = nil
+		interp := newTestInterpreter(bindings)
+		_, err := interp.Interpret(`
+		f := func() {
+			defer record(1)
+			defer record(2)
+			record(0)
+		}
+		f()
+		record(3)
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(logs, []any{0, 2, 1, 3}) {
+			t.Errorf("Expected [0 2 1 3], got %v", logs)
+		}
+	})
+
+	// 参数在defer语句处求值
+	t.Run("Args Evaluated At Defer Site", func(t *testing.T) {
+		logs = nil
+		interp := newTestInterpreter(bindings)
+		_, err := interp.Interpret(`
+		x := 1
+		defer record(x)
+		x = 2
+		record(x)
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(logs, []any{2, 1}) {
+			t.Errorf("Expected [2 1], got %v", logs)
+		}
+	})
+
+	// 延迟调用的闭包可以访问函数体中的变量
+	t.Run("Deferred Closure", func(t *testing.T) {
+		logs = nil
+		interp := newTestInterpreter(bindings)
+		_, err := interp.Interpret(`
+		f := func() {
+			name := "inner"
+			defer func() {
+				record(name)
+			}()
+			name = "changed"
+		}
+		f()
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(logs, []any{"changed"}) {
+			t.Errorf("Expected [changed], got %v", logs)
+		}
+	})
+
+	// 出错时仍然执行defer
+	t.Run("Error Path", func(t *testing.T) {
+		logs = nil
+		interp := newTestInterpreter(bindings)
+		_, err := interp.Interpret(`
+		defer record("main")
+		f := func() {
+			defer record("f")
+			x := 1 / 0
+		}
+		f()
+		record("unreachable")
+		`)
+		if err == nil {
+			t.Fatalf("Expected error")
+		}
+		if !reflect.DeepEqual(logs, []any{"f", "main"}) {
+			t.Errorf("Expected [f main], got %v", logs)
+		}
+	})
+
+	// go运行时panic（向nil map赋值）时仍然执行defer
+	t.Run("Runtime Panic", func(t *testing.T) {
+		logs = nil
+		interp := newTestInterpreter(bindings)
+		_, err := interp.Interpret(`
+		defer record("main")
+		f := func() {
+			defer record("f")
+			var m map[string]int
+			m["a"] = 1
+		}
+		f()
+		record("unreachable")
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Fatalf("Expected panic error, got %v", err)
+		}
+		if !reflect.DeepEqual(logs, []any{"f", "main"}) {
+			t.Errorf("Expected [f main], got %v", logs)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/scanner"
 	"go/token"
 	"reflect"
 	"strconv"
@@ -12,7 +13,32 @@ import (
 
 // 因为类型是有限的，所以可以做一个全局的缓存
 var globalReflectCache = NewReflectCache()
-var emptyReturn = &struct{ emptyReturn bool }{true}
+
+// tuple 多返回值，函数返回多个值时使用
+type tuple []any
+
+// controlFlow 控制流信号，由 return、break、continue 语句产生
+// 信号沿着语句列表逐层向外传递，直到被对应的循环、switch 或函数处理；普通语句的值不会向外传递
+type controlFlow interface {
+	controlFlow()
+}
+
+// returnValues return语句的结果，用于和普通语句的值区分
+type returnValues []any
+
+func (returnValues) controlFlow() {}
+
+// 将return的结果转换为函数调用的值，多个返回值时为 tuple
+func (r returnValues) value() any {
+	switch len(r) {
+	case 0:
+		return nil
+	case 1:
+		return r[0]
+	default:
+		return tuple(r)
+	}
+}
 
 type Function struct {
 	params  []*ast.Field
@@ -20,12 +46,43 @@ type Function struct {
 	body    *ast.BlockStmt
 }
 
+// callFrame 记录一次函数调用（或脚本主体）执行期间的运行时信息
+type callFrame struct {
+	// 调用者的帧
+	parent *callFrame
+	// defer 注册的延迟调用，函数退出时按后进先出的顺序执行
+	defers []deferredCall
+	// 正在执行延迟调用
+	deferring bool
+	// 尚未被recover的panic
+	panic *PanicError
+	// panic 已被延迟调用中的recover捕获
+	recovered bool
+}
+
+// deferredCall 延迟调用，函数和参数在 defer 语句处就已经求值
+type deferredCall struct {
+	call *ast.CallExpr
+	fn   any
+	args []any
+}
+
 type Interpreter struct {
 	// sharedScope *SharedScope
 	scope    *Scope
+	frame    *callFrame
 	global   any
 	astCache *astCache
+	// 正在执行的程序的FileSet，用于还原源码位置
+	fset    *token.FileSet
+	backend Backend
+	// 正在执行的程序编译后的语句，只在使用 BackendClosure 或 BackendBytecode 时存在
+	compiled map[ast.Node]evalFunc
+	// 函数体中变量的布局，只在使用 BackendClosure 时存在
+	layouts  map[*ast.BlockStmt]*scopeLayout
 	isForked bool
+	// 脚本启动的goroutine中未处理的错误和panic的处理函数
+	goroutineError func(err error)
 }
 
 // func NewInterpreterWithSharedScope(sharedScope map[string]any) *Interpreter {
@@ -44,7 +101,8 @@ func NewInterpreter() *Interpreter {
 		scope:  &Scope{},
 		global: nil,
 		astCache: &astCache{
-			cache: make(map[string]*ast.File),
+			cache: make(map[string]*Program),
+			fset:  token.NewFileSet(),
 		},
 	}
 
@@ -58,40 +116,28 @@ func NewInterpreter() *Interpreter {
 func (i *Interpreter) getZeroValue(typeExpr ast.Expr) (any, error) {
 	switch t := typeExpr.(type) {
 	case *ast.Ident:
-		switch t.Name {
-		case "string":
-			return "", nil
-		case "bool":
-			return false, nil
-		case "int", "int32", "int64", "int16", "int8":
-			return 0, nil
-		case "uint", "uint32", "uint64", "uint16", "uint8":
-			return uint(0), nil
-		case "float32", "float64":
-			return 0.0, nil
-		case "byte":
-			return byte(0), nil
-		case "rune":
-			return rune(0), nil
-		case "interface{}":
-			return nil, nil
-		case "error":
-			return nil, nil
-		default:
+		// 与 resolveType 使用相同的类型，接口类型的零值为 nil
+		typ, err := i.resolveType(t)
+		if err != nil {
 			return nil, fmt.Errorf("不支持的类型: %s", t.Name)
 		}
+		return assertZero(typ), nil
 	case *ast.ArrayType:
 		// 返回空切片
 		return []any{}, nil
 	case *ast.MapType:
 		// 返回空map
 		return make(map[any]any), nil
-	case *ast.StarExpr:
-		// 指针类型零值为nil
+	case *ast.StarExpr, *ast.ChanType:
+		// 指针和channel类型零值为nil
 		return nil, nil
 	case *ast.StructType:
-		// 返回空map表示struct
-		return make(map[string]any), nil
+		// 匿名结构体
+		typ, err := i.structOf("", t)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.Zero(typ).Interface(), nil
 	case *ast.InterfaceType:
 		return nil, nil
 	default:
@@ -106,8 +152,10 @@ func (i *Interpreter) Fork() *Interpreter {
 		scope:  globalScope,
 		global: i.global,
 		// 共享
-		astCache: i.astCache,
-		isForked: true,
+		astCache:       i.astCache,
+		backend:        i.backend,
+		goroutineError: i.goroutineError,
+		isForked:       true,
 	}
 }
 
@@ -135,13 +183,29 @@ func (i *Interpreter) Get(name string) any {
 	currentScope := i.scope
 	for currentScope != nil {
 		if v, ok := currentScope.Load(name); ok {
-			return v
+			return unbox(v)
 		}
 		currentScope = currentScope.parent
 	}
 	return nil
 }
 
+// 作用域链中是否绑定了该名字，脚本中声明的和宿主设置的同名变量会覆盖内置函数
+func (i *Interpreter) bound(name string) bool {
+	_, ok := i.load(name)
+	return ok
+}
+
+// 在作用域链中查找名字，返回作用域中保存的值，装箱的变量返回 *varBox 本身而不读取它的值
+func (i *Interpreter) load(name string) (any, bool) {
+	for currentScope := i.scope; currentScope != nil; currentScope = currentScope.parent {
+		if value, ok := currentScope.Load(name); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
 func (i *Interpreter) SetGlobal(obj any) {
 	refVal := reflect.ValueOf(obj)
 	if refVal.Kind() == reflect.Struct {
@@ -157,9 +221,32 @@ func (i *Interpreter) GetGlobal() any {
 	return i.global
 }
 
+// 如果 src[start] 开始的单引号内容是合法的rune字面量，返回结束的单引号的位置，否则返回 -1
+func runeLiteralEnd(src string, start int) int {
+	end := start + 1
+	for ; end < len(src) && src[end] != '\'' && src[end] != '\n'; end++ {
+		if src[end] == '\\' {
+			end++
+		}
+	}
+	if end >= len(src) || src[end] != '\'' {
+		return -1
+	}
+	content := src[start+1 : end]
+	if content == "" {
+		return -1
+	}
+	if _, _, tail, err := strconv.UnquoteChar(content, '\''); err != nil || tail != "" {
+		return -1
+	}
+	return end
+}
+
 // 将单引号包裹的字符串替换为双引号包裹，同时避免替换注释、其它字符串或字符中的引号
 // 该函数会遍历源代码，识别字符串/注释上下文，只有在顶层遇到单引号时才视为字符串开始/结束
 // 支持反斜杠转义，例如 '\' 或 '\""
+// 单引号中恰好是一个字符或一个转义序列时（如 'a'、'\n'、'\u00e9'），按go的规则保留为rune字面量，
+// 其余情况（内容为空或多于一个字符，如 'ab'）作为字符串；需要单个字符的字符串时使用双引号
 func preprocessSingleQuoteString(src string) string {
 	var b strings.Builder
 	inSingle, inDouble, inBacktick := false, false, false
@@ -249,6 +336,11 @@ func preprocessSingleQuoteString(src string) string {
 		}
 
 		if c == '\'' {
+			if end := runeLiteralEnd(src, i); end > 0 {
+				b.WriteString(src[i : end+1]) // rune字面量保持原样
+				i = end
+				continue
+			}
 			b.WriteByte('"') // 单引号字符串开始
 			inSingle = true
 			continue
@@ -271,47 +363,217 @@ func preprocessSingleQuoteString(src string) string {
 	return b.String()
 }
 
-func (i *Interpreter) Interpret(code string) (any, error) {
-	// 预处理单引号字符串
-	code = preprocessSingleQuoteString(code)
+// 包装脚本时在用户代码之前插入的行数，用于还原脚本中的行号
+const scriptHeaderLines = 2
+
+// 将脚本包装为一个go源文件
+// 脚本主体放在 __main__ 函数中，顶层的函数和类型声明被移出 __main__ 成为包级声明：
+// 在声明前关闭 __main__，声明后再打开一个新的 __main__，这样不会改变脚本中的行号
+func wrapScript(code string) string {
+	var b strings.Builder
+	b.WriteString("package main\nfunc __main__() any {\n")
+	last := 0
+	for _, span := range topLevelDecls(code) {
+		b.WriteString(code[last:span[0]])
+		b.WriteString("};")
+		b.WriteString(code[span[0]:span[1]])
+		b.WriteString(";func __main__() any {")
+		last = span[1]
+	}
+	b.WriteString(code[last:])
+	b.WriteString("\n}\n")
+	return b.String()
+}
+
+// 查找脚本顶层的函数声明 func name(...) {...}、方法声明和类型声明 type ...，返回每个声明在源码中的起止偏移
+func topLevelDecls(code string) [][2]int {
 	fset := token.NewFileSet()
-	code = `package main
-	func __main__() any {	
-	` + code + `
-	}
-	`
-	// fmt.Println(code)
-	astFile, err := i.astCache.GetIfNotExist(code, func() (*ast.File, error) {
-		return parser.ParseFile(fset, "", code, parser.Mode(0))
+	file := fset.AddFile("", fset.Base(), len(code))
+	var s scanner.Scanner
+	// 语法错误留给解析器报告
+	s.Init(file, []byte(code), func(token.Position, string) {}, 0)
+
+	type item struct {
+		pos token.Pos
+		tok token.Token
+	}
+	var toks []item
+	for {
+		pos, tok, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		toks = append(toks, item{pos, tok})
+	}
+
+	var spans [][2]int
+	depth := 0
+	for n := 0; n < len(toks); n++ {
+		switch toks[n].tok {
+		case token.LBRACE, token.LPAREN, token.LBRACK:
+			depth++
+			continue
+		case token.RBRACE, token.RPAREN, token.RBRACK:
+			depth--
+			continue
+		case token.FUNC, token.TYPE:
+		default:
+			continue
+		}
+		// 只处理位于语句开头的声明
+		if depth != 0 || n > 0 && toks[n-1].tok != token.SEMICOLON || n+1 >= len(toks) {
+			continue
+		}
+		if toks[n].tok == token.TYPE {
+			// 类型声明到同一层级的分号为止
+			start, inner := n, 0
+			for ; n < len(toks); n++ {
+				switch toks[n].tok {
+				case token.LBRACE, token.LPAREN, token.LBRACK:
+					inner++
+				case token.RBRACE, token.RPAREN, token.RBRACK:
+					inner--
+				}
+				if inner == 0 && toks[n].tok == token.SEMICOLON {
+					break
+				}
+			}
+			if n >= len(toks) {
+				break
+			}
+			spans = append(spans, [2]int{file.Offset(toks[start].pos), file.Offset(toks[n].pos)})
+			continue
+		}
+		if toks[n+1].tok == token.LPAREN {
+			// 方法声明 func (recv) name(...)，与函数字面量 func(...) 区分
+			r, inner := n+1, 0
+			for ; r < len(toks); r++ {
+				if toks[r].tok == token.LPAREN {
+					inner++
+				} else if toks[r].tok == token.RPAREN {
+					inner--
+				}
+				if inner == 0 {
+					break
+				}
+			}
+			if r+2 >= len(toks) || toks[r+1].tok != token.IDENT || toks[r+2].tok != token.LPAREN && toks[r+2].tok != token.LBRACK {
+				continue
+			}
+		} else if toks[n+1].tok != token.IDENT {
+			continue
+		}
+		// 找到函数体的左大括号，跳过 interface{} 和 struct{...} 类型中的大括号
+		start := n
+		inner := 0
+		for n++; n < len(toks); n++ {
+			tok := toks[n].tok
+			if tok == token.LBRACE && inner == 0 && toks[n-1].tok != token.INTERFACE && toks[n-1].tok != token.STRUCT {
+				break
+			}
+			switch tok {
+			case token.LBRACE, token.LPAREN, token.LBRACK:
+				inner++
+			case token.RBRACE, token.RPAREN, token.RBRACK:
+				inner--
+			}
+		}
+		// 找到与之匹配的右大括号
+		for ; n < len(toks); n++ {
+			switch toks[n].tok {
+			case token.LBRACE:
+				inner++
+			case token.RBRACE:
+				inner--
+			}
+			if inner == 0 {
+				break
+			}
+		}
+		if n >= len(toks) {
+			break
+		}
+		spans = append(spans, [2]int{file.Offset(toks[start].pos), file.Offset(toks[n].pos) + 1})
+	}
+	return spans
+}
+
+// 解析包装后的脚本，将各段 __main__ 合并为一个函数体
+func parseScript(fset *token.FileSet, code string) (*ast.File, error) {
+	astFile, err := parser.ParseFile(fset, "", code, parser.Mode(0))
+	if err != nil {
+		return nil, err
+	}
+	var main *ast.FuncDecl
+	decls := astFile.Decls[:0]
+	for _, decl := range astFile.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Name.Name == "__main__" {
+			if main != nil {
+				main.Body.List = append(main.Body.List, funcDecl.Body.List...)
+				continue
+			}
+			main = funcDecl
+		}
+		decls = append(decls, decl)
+	}
+	astFile.Decls = decls
+	if main != nil {
+		implicitReturn(main.Body)
+	}
+	return astFile, nil
+}
+
+// 脚本主体的最后一条语句是表达式时，它的值作为 Interpret 的结果，相当于隐式的 return
+func implicitReturn(body *ast.BlockStmt) {
+	if len(body.List) == 0 {
+		return
+	}
+	if expr, ok := body.List[len(body.List)-1].(*ast.ExprStmt); ok {
+		body.List[len(body.List)-1] = &ast.ReturnStmt{Return: expr.Pos(), Results: []ast.Expr{expr.X}}
+	}
+}
+
+// Interpret 编译并执行脚本，相同的脚本只编译一次
+func (i *Interpreter) Interpret(code string) (result any, err error) {
+	prog, err := i.astCache.GetIfNotExist(code, func() (*Program, error) {
+		return compile(i.astCache.fset, code)
 	})
 	if err != nil {
 		return nil, err
 	}
-	return i.eval(astFile.Decls[0].(*ast.FuncDecl).Body)
-	// 首先处理所有函数定义
-	// for _, decl := range f.Decls {
-	// 	if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-	// 		if funcDecl.Name.Name != "__main__" {
-	// 			i.userFuncs[funcDecl.Name.Name] = &Function{
-	// 				params: funcDecl.Type.Params.List,
-	// 				body:   funcDecl.Body,
-	// 			}
-	// 		}
-	// 	}
-	// }
-
-	// // 查找并执行 __main__ 函数
-	// for _, decl := range f.Decls {
-	// 	if funcDecl, ok := decl.(*ast.FuncDecl); ok {
-	// 		if funcDecl.Name.Name == "__main__" {
-	// 			return i.eval(funcDecl.Body)
-	// 		}
-	// 	}
-	// }
-	// return nil, fmt.Errorf("没有找到 __main__ 函数")
+	return i.Run(prog)
+}
+
+// Call 调用脚本中定义的函数或者绑定的函数，多个返回值时以 []any 的形式返回
+func (i *Interpreter) Call(name string, args ...any) (result any, err error) {
+	fn := i.Get(name)
+	if fn == nil {
+		return nil, fmt.Errorf("未定义的函数: %s", name)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &PanicError{Value: r, Func: name}
+		}
+	}()
+	result, err = i.callFunction(nil, fn, args)
+	if t, ok := result.(tuple); ok {
+		result = []any(t)
+	}
+	return result, err
 }
 
 func (i *Interpreter) eval(node ast.Node) (any, error) {
+	// 编译过的语句直接执行编译后的闭包或字节码
+	if i.compiled != nil {
+		if fn, ok := i.compiled[node]; ok {
+			return fn(i)
+		}
+	}
+	return i.walk(node)
+}
+
+// 遍历AST执行节点
+func (i *Interpreter) walk(node ast.Node) (any, error) {
 	switch n := node.(type) {
 	case *ast.BasicLit:
 		return i.evalBasicLit(n)
@@ -320,33 +582,9 @@ func (i *Interpreter) eval(node ast.Node) (any, error) {
 	case *ast.BinaryExpr:
 		return i.evalBinaryExpr(n)
 	case *ast.CallExpr:
-		// 处理 make 内置函数
-		if ident, ok := n.Fun.(*ast.Ident); ok && ident.Name == "make" {
-			if len(n.Args) == 0 {
-				return nil, fmt.Errorf("make 需要至少一个参数")
-			}
-
-			switch t := n.Args[0].(type) {
-			case *ast.MapType:
-				// 创建新的 map
-				return make(map[string]any), nil
-			case *ast.ArrayType:
-				// 创建新的 slice
-				size := 0
-				if len(n.Args) > 1 {
-					// 如果提供了大小参数
-					sizeVal, err := i.eval(n.Args[1])
-					if err != nil {
-						return nil, err
-					}
-					if sizeInt, ok := sizeVal.(int); ok {
-						size = sizeInt
-					}
-				}
-				return make([]any, size), nil
-			default:
-				return nil, fmt.Errorf("不支持的 make 类型: %T", t)
-			}
+		// 处理需要解释器直接处理的内置函数，名字没有被作用域中的变量覆盖时才是内置函数
+		if ident, ok := n.Fun.(*ast.Ident); ok && builtins[ident.Name] && !i.bound(ident.Name) {
+			return i.evalBuiltin(n, ident.Name)
 		}
 		return i.evalCallExpr(n)
 	case *ast.ParenExpr:
@@ -354,7 +592,7 @@ func (i *Interpreter) eval(node ast.Node) (any, error) {
 	case *ast.BlockStmt:
 		return i.evalBlockStmt(n)
 	case *ast.ForStmt:
-		return i.evalForStmt(n)
+		return i.evalForStmt(n, "")
 	case *ast.IfStmt:
 		return i.evalIfStmt(n)
 	case *ast.AssignStmt:
@@ -373,6 +611,21 @@ func (i *Interpreter) eval(node ast.Node) (any, error) {
 		return i.evalKeyValueExpr(n)
 	case *ast.IndexExpr:
 		return i.evalIndexExpr(n)
+	case *ast.IndexListExpr:
+		// 泛型函数的实例化 f[K, V]
+		fn, err := i.eval(n.X)
+		if err != nil {
+			return nil, err
+		}
+		g, ok := fn.(*genericFunc)
+		if !ok {
+			return nil, fmt.Errorf("%T 不是泛型函数", fn)
+		}
+		return i.instantiate(g, n.Indices)
+	case *ast.SliceExpr:
+		return i.evalSliceExpr(n)
+	case *ast.StarExpr:
+		return i.evalStarExpr(n)
 	case *ast.SelectorExpr:
 		return i.evalSelectorExpr(n)
 	case *ast.DeclStmt:
@@ -383,11 +636,25 @@ func (i *Interpreter) eval(node ast.Node) (any, error) {
 	case *ast.BranchStmt:
 		return i.evalBranchStmt(n)
 	case *ast.RangeStmt:
-		return i.evalRangeStmt(n)
+		return i.evalRangeStmt(n, "")
 	case *ast.UnaryExpr:
 		return i.evalUnaryExpr(n)
 	case *ast.SwitchStmt:
-		return i.evalSwitchStmt(n)
+		return i.evalSwitchStmt(n, "")
+	case *ast.TypeSwitchStmt:
+		return i.evalTypeSwitchStmt(n, "")
+	case *ast.LabeledStmt:
+		return i.evalLabeledStmt(n)
+	case *ast.TypeAssertExpr:
+		return i.evalTypeAssertExpr(n)
+	case *ast.DeferStmt:
+		return i.evalDeferStmt(n)
+	case *ast.GoStmt:
+		return i.evalGoStmt(n)
+	case *ast.SendStmt:
+		return i.evalSendStmt(n)
+	case *ast.SelectStmt:
+		return i.evalSelectStmt(n, "")
 	default:
 		return nil, fmt.Errorf("unsupported node type: %T", node)
 	}
@@ -395,19 +662,34 @@ func (i *Interpreter) eval(node ast.Node) (any, error) {
 
 // 基础类型处理
 func (i *Interpreter) evalBasicLit(lit *ast.BasicLit) (any, error) {
+	return literalValue(lit)
+}
+
+// 字面量的值
+func literalValue(lit *ast.BasicLit) (any, error) {
 	switch lit.Kind {
 	case token.INT:
-		return strconv.Atoi(lit.Value)
+		return parseIntLit(lit.Value)
 	case token.STRING:
-		// 处理字符串字面量，支持双引号和反引号
-		if strings.HasPrefix(lit.Value, "`") && strings.HasSuffix(lit.Value, "`") {
-			// 反引号字符串，直接去掉首尾的反引号
-			return lit.Value[1 : len(lit.Value)-1], nil
+		// 处理字符串字面量，支持双引号和反引号，按go的规则处理转义
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return nil, fmt.Errorf("无效的字符串字面量: %s", lit.Value)
+		}
+		return value, nil
+	case token.CHAR:
+		// rune字面量
+		value, _, _, err := strconv.UnquoteChar(lit.Value[1:len(lit.Value)-1], '\'')
+		if err != nil {
+			return nil, fmt.Errorf("无效的rune字面量: %s", lit.Value)
 		}
-		// 双引号字符串，去掉首尾的双引号
-		return strings.Trim(lit.Value, `"`), nil
+		return value, nil
 	case token.FLOAT:
-		return strconv.ParseFloat(lit.Value, 64)
+		f, err := strconv.ParseFloat(lit.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的浮点数字面量: %s", lit.Value)
+		}
+		return f, nil
 	default:
 		return nil, fmt.Errorf("unsupported literal type: %s", lit.Kind)
 	}
@@ -431,7 +713,7 @@ func (i *Interpreter) evalIdent(ident *ast.Ident) (any, error) {
 	currentScope := i.scope
 	for currentScope != nil {
 		if val, ok := currentScope.Load(ident.Name); ok {
-			return val, nil
+			return unbox(val), nil
 		}
 		currentScope = currentScope.parent
 	}
@@ -454,7 +736,7 @@ func (i *Interpreter) evalIdent(ident *ast.Ident) (any, error) {
 			// }
 			// 如果是slice
 			if v.Kind() == reflect.Struct {
-				if item, _ := globalReflectCache.get(g, ident.Name); item != nil {
+				if item, typ := globalReflectCache.get(g, ident.Name); typ != nil {
 					return item, nil
 				}
 				// if field := v.FieldByName(ident.Name); field.IsValid() {
@@ -489,33 +771,177 @@ func (i *Interpreter) evalIdent(ident *ast.Ident) (any, error) {
 
 // 处理代码块
 func (i *Interpreter) evalBlockStmt(block *ast.BlockStmt) (any, error) {
-	// 创建新的作用域
-	prevScope := i.scope
-	i.scope = &Scope{
-		parent: prevScope,
-	}
-	defer func() { i.scope = prevScope }() // 确保作用域恢复
-
-	var result any
-	var err error
+	// 在新的作用域中执行
+	return i.withScope(&Scope{parent: i.scope}).evalStmtList(block.List)
+}
 
-	for _, stmt := range block.List {
-		result, err = i.eval(stmt)
+// 在当前作用域中依次执行语句，遇到控制流信号时停止执行并返回该信号
+// goto 的目标在当前语句列表中时从目标语句继续执行，否则继续向外层的语句列表查找
+// 语句执行中发生的go运行时panic（如向nil map赋值）转换为脚本的panic，位置是发生panic的语句，
+// 这样函数的defer仍然会执行，脚本也可以recover
+func (i *Interpreter) evalStmtList(list []ast.Stmt) (result any, err error) {
+	var current ast.Stmt
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, i.runtimePanic(r, current)
+		}
+	}()
+	for idx := 0; idx < len(list); idx++ {
+		current = list[idx]
+		result, err := i.eval(current)
 		if err != nil {
 			return nil, err
 		}
+		if _, ok := result.(controlFlow); ok {
+			if g, ok := result.(gotoSentinel); ok {
+				if target := labelIndex(list, g.label); target >= 0 {
+					idx = target - 1
+					continue
+				}
+			}
+			return result, nil
+		}
 	}
-	return result, nil
+	return nil, nil
+}
+
+// 在给定的作用域中执行函数体，并在退出时（包括出错时）按后进先出的顺序执行 defer
+// 函数体与参数共享同一个作用域，这样延迟调用的闭包可以访问函数体中定义的变量
+// 函数体中未被recover的panic会在defer执行完后继续向调用者传播
+// 对于命名返回值，return的结果先赋值给返回变量再执行defer，defer中对返回变量的修改会体现在返回值中
+// caller 是调用者的帧，决定了recover能否捕获调用者的panic
+func (i *Interpreter) evalFuncBody(body *ast.BlockStmt, scope *Scope, caller *callFrame, results []*ast.Field) (result any, err error) {
+	frame := &callFrame{parent: caller}
+	i = i.withScope(scope)
+	i.frame = frame
+
+	// 返回值的数量和命名返回值
+	arity := 0
+	var names []string
+	for _, field := range results {
+		if len(field.Names) == 0 {
+			arity++
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+			arity++
+		}
+	}
+
+	result, err = i.evalStmtList(body.List)
+	switch r := result.(type) {
+	case breakSentinel, continueSentinel:
+		result, err = nil, fmt.Errorf("break 或 continue 不在循环中")
+	case gotoSentinel:
+		result, err = nil, fmt.Errorf("goto 的标签 %s 未定义", r.label)
+	case fallthroughSentinel:
+		result, err = nil, fmt.Errorf("fallthrough 只能用于 switch 的 case 的最后一条语句")
+	}
+	if ret, ok := result.(returnValues); ok {
+		result = ret.value()
+		if err == nil && len(ret) > 0 && arity > 0 {
+			values, verr := expandValues(ret, arity)
+			if verr != nil {
+				return nil, fmt.Errorf("返回值数量不匹配: %v", verr)
+			}
+			// 将return的结果赋值给命名返回值
+			for idx, name := range names {
+				scope.Store(name, values[idx])
+			}
+		}
+	}
+	if p, ok := err.(*PanicError); ok {
+		frame.panic, err = p, nil
+	}
+	if derr := i.runDefers(frame); derr != nil {
+		err = derr
+	}
+	if frame.panic != nil {
+		return nil, frame.panic
+	}
+	if frame.recovered {
+		// 从panic中恢复的函数正常返回零值
+		result = nil
+	}
+	if err == nil && len(names) > 0 {
+		// 返回命名返回值的当前值
+		values := make(returnValues, len(names))
+		for idx, name := range names {
+			value, _ := scope.Load(name)
+			values[idx] = unbox(value)
+		}
+		result = values.value()
+	}
+	return result, err
+}
+
+// 处理defer语句，函数和参数在此处求值，调用推迟到函数退出时
+func (i *Interpreter) evalDeferStmt(stmt *ast.DeferStmt) (any, error) {
+	if i.frame == nil {
+		return nil, fmt.Errorf("defer 只能在函数体中使用")
+	}
+	fn, err := i.evalCallee(stmt.Call.Fun)
+	if err != nil {
+		return nil, err
+	}
+	args, err := i.evalArgs(stmt.Call.Args)
+	if err != nil {
+		return nil, err
+	}
+	i.frame.defers = append(i.frame.defers, deferredCall{call: stmt.Call, fn: fn, args: args})
+	return nil, nil
+}
+
+// 按后进先出的顺序执行延迟调用，返回最后一个出错的延迟调用的错误
+// 延迟调用中发生的panic会替换当前的panic，与go一致
+func (i *Interpreter) runDefers(frame *callFrame) error {
+	var err error
+	frame.deferring = true
+	for len(frame.defers) > 0 {
+		d := frame.defers[len(frame.defers)-1]
+		frame.defers = frame.defers[:len(frame.defers)-1]
+		_, derr := i.callFunction(d.call, d.fn, d.args)
+		if p, ok := derr.(*PanicError); ok {
+			frame.panic, frame.recovered = p, false
+		} else if derr != nil {
+			err = derr
+		}
+	}
+	frame.deferring = false
+	return err
+}
+
+// recover 内置函数，只有在延迟调用的函数中直接调用才能捕获panic
+func (i *Interpreter) recover() any {
+	if i.frame == nil || i.frame.parent == nil {
+		return nil
+	}
+	caller := i.frame.parent
+	if !caller.deferring || caller.panic == nil {
+		return nil
+	}
+	value := caller.panic.Value
+	caller.panic, caller.recovered = nil, true
+	return value
 }
 
-// 处理for循环
-func (i *Interpreter) evalForStmt(f *ast.ForStmt) (any, error) {
-	// 初始化语句
+// 处理for循环，与go一样初始化语句中声明的变量在每次迭代中都是新的变量，闭包捕获的是当次迭代的值
+func (i *Interpreter) evalForStmt(f *ast.ForStmt, label string) (any, error) {
+	// 初始化语句在循环自己的作用域中执行
+	i = i.withScope(&Scope{parent: i.scope})
+	var names []string
 	if f.Init != nil {
 		_, err := i.eval(f.Init)
 		if err != nil {
 			return nil, err
 		}
+		if assign, ok := f.Init.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+			for _, lhs := range assign.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name != "_" {
+					names = append(names, ident.Name)
+				}
+			}
+		}
 	}
 
 	for {
@@ -530,25 +956,23 @@ func (i *Interpreter) evalForStmt(f *ast.ForStmt) (any, error) {
 			}
 		}
 
-		// 执行循环体
+		// 执行循环体，处理 break、continue 和 return
 		result, err := i.eval(f.Body)
 		if err != nil {
 			return nil, err
 		}
+		if exit, signal := loopControl(result, label); exit {
+			return signal, nil
+		}
 
-		// 处理 break 和 continue
-		switch result.(type) {
-		case breakSentinel:
-			return nil, nil
-		case continueSentinel:
-			// 跳过后续处理，直接进入下一次循环
-			if f.Post != nil {
-				_, err := i.eval(f.Post)
-				if err != nil {
-					return nil, err
-				}
+		// 下一次迭代使用新的变量，初始值为本次迭代结束时的值
+		if len(names) > 0 {
+			next := &Scope{parent: i.scope.parent}
+			for _, name := range names {
+				value, _ := i.scope.Load(name)
+				next.Store(name, unbox(value))
 			}
-			continue
+			i = i.withScope(next)
 		}
 
 		// 执行后续操作
@@ -564,8 +988,9 @@ func (i *Interpreter) evalForStmt(f *ast.ForStmt) (any, error) {
 
 // 处理if语句
 func (i *Interpreter) evalIfStmt(ifStmt *ast.IfStmt) (any, error) {
-	// 初始化语句（如 if x := ...; x > 0 {}）
+	// 初始化语句（如 if x := ...; x > 0 {}），声明的变量只在if语句中可见
 	if ifStmt.Init != nil {
+		i = i.withScope(&Scope{parent: i.scope})
 		_, err := i.eval(ifStmt.Init)
 		if err != nil {
 			return nil, err
@@ -589,341 +1014,656 @@ func (i *Interpreter) evalIfStmt(ifStmt *ast.IfStmt) (any, error) {
 // 处理赋值语句
 func (i *Interpreter) evalAssignStmt(assign *ast.AssignStmt) (any, error) {
 	// 处理右侧表达式
-	values := make([]any, len(assign.Rhs))
-	for idx, expr := range assign.Rhs {
-		val, err := i.eval(expr)
-		if err != nil {
-			return nil, err
-		}
-		values[idx] = val
+	values, err := i.evalValues(assign.Rhs, len(assign.Lhs))
+	if err != nil {
+		return nil, err
 	}
 
 	switch assign.Tok {
 	case token.DEFINE, token.ASSIGN: // := 或 =
 		for idx, lhs := range assign.Lhs {
-			switch l := lhs.(type) {
-			case *ast.Ident:
-				if assign.Tok == token.DEFINE {
-					i.scope.Store(l.Name, values[idx])
-				} else {
-					// 查找变量并赋值
-					currentScope := i.scope
-					for currentScope != nil {
-						if _, ok := currentScope.Load(l.Name); ok {
-							currentScope.Store(l.Name, values[idx])
-							break
-						}
-						currentScope = currentScope.parent
-					}
-				}
-			case *ast.IndexExpr:
-				// 获取容器
-				container, err := i.eval(l.X)
-				if err != nil {
-					return nil, err
-				}
-
-				// 获取索引
-				index, err := i.eval(l.Index)
-				if err != nil {
-					return nil, err
-				}
-
-				// 根据容器类型进行赋值
-				switch c := container.(type) {
-				case map[any]any:
-					c[index] = values[idx]
-				case map[string]any:
-					if strKey, ok := index.(string); ok {
-						c[strKey] = values[idx]
-					} else {
-						return nil, fmt.Errorf("map键必须是字符串类型")
-					}
-				case []any:
-					if intIndex, ok := index.(int); ok {
-						if intIndex < 0 || intIndex >= len(c) {
-							return nil, fmt.Errorf("索引越界")
-						}
-						c[intIndex] = values[idx]
-					} else {
-						return nil, fmt.Errorf("slice索引必须是整数")
-					}
-				default:
-					return nil, fmt.Errorf("不支持的索引赋值操作: %T", container)
-				}
-			case *ast.SelectorExpr:
-				// 获取容器
-				container, err := i.eval(l.X)
-				if err != nil {
-					return nil, err
-				}
-
-				// 根据容器类型进行赋值
-				switch c := container.(type) {
-				case map[any]any:
-					c[l.Sel.Name] = values[idx]
-				case map[string]any:
-					c[l.Sel.Name] = values[idx]
-				default:
-					// 使用反射缓存处理结构体字段赋值
-					if err := globalReflectCache.set(container, l.Sel.Name, values[idx]); err != nil {
-						return nil, err
-					}
-					// item := globalReflectCache.analyze(container)
-					// if fieldInfo, ok := item.fields[l.Sel.Name]; ok {
-					// 	v := reflect.ValueOf(container)
-					// 	var base unsafe.Pointer
-					// 	if v.Kind() == reflect.Ptr {
-					// 		base = unsafe.Pointer(v.Pointer())
-					// 	} else {
-					// 		// 如果不是指针，创建一个临时指针
-					// 		ptr := reflect.New(v.Type())
-					// 		ptr.Elem().Set(v)
-					// 		base = unsafe.Pointer(ptr.Pointer())
-					// 		// 注意：这种情况下修改不会影响原始值，因为我们修改的是副本
-					// 		// 可能需要返回错误或警告
-					// 		return nil, fmt.Errorf("无法修改非指针结构体的字段: %s", l.Sel.Name)
-					// 	}
-
-					// 	// 获取字段的指针
-					// 	ptr := unsafe.Pointer(uintptr(base) + fieldInfo.offset)
-					// 	field := reflect.NewAt(fieldInfo.typ, ptr).Elem()
-
-					// 	if !field.CanSet() {
-					// 		return nil, fmt.Errorf("结构体字段 %s 不可写入（可能是未导出字段）", l.Sel.Name)
-					// 	}
-
-					// 	// 尝试设置字段值
-					// 	fieldValue := reflect.ValueOf(values[idx])
-					// 	if fieldValue.Type().AssignableTo(field.Type()) {
-					// 		field.Set(fieldValue)
-					// 		return values, nil
-					// 	}
-
-					// 	return nil, fmt.Errorf("类型不匹配：无法将 %T 赋值给 %s", values[idx], field.Type())
-					// }
-					// return nil, fmt.Errorf("不支持的选择器赋值操作: %T 没有字段 %s", container, l.Sel.Name)
-				}
-			default:
-				return nil, fmt.Errorf("不支持的赋值目标类型: %T", l)
-			}
-		}
-	case token.ADD_ASSIGN: // +=
-		for idx, lhs := range assign.Lhs {
-			ident, ok := lhs.(*ast.Ident)
-			if !ok {
-				return nil, fmt.Errorf("非左值表达式")
-			}
-			// 获取当前值
-			currentVal, err := i.evalIdent(ident)
-			if err != nil {
+			if err := i.assign(lhs, values[idx], assign.Tok == token.DEFINE); err != nil {
 				return nil, err
 			}
-			// 计算新值
-			newVal, err := add(currentVal, values[idx])
-			if err != nil {
-				return nil, err
-			}
-			// 更新值
-			currentScope := i.scope
-			for currentScope != nil {
-				if _, ok := currentScope.Load(ident.Name); ok {
-					currentScope.Store(ident.Name, newVal)
-					break
-				}
-				currentScope = currentScope.parent
-			}
 		}
 	default:
-		return nil, fmt.Errorf("不支持的赋值操作符: %s", assign.Tok)
+		// 复合赋值 x op= y
+		op, ok := compoundOps[assign.Tok]
+		if !ok {
+			return nil, fmt.Errorf("不支持的赋值操作符: %s", assign.Tok)
+		}
+		if len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			return nil, fmt.Errorf("%s 只能用于单个变量", assign.Tok)
+		}
+		if err := i.compoundAssign(assign.Lhs[0], op, values[0], assign.Rhs[0]); err != nil {
+			return nil, err
+		}
 	}
 
-	return values, nil
+	return nil, nil
 }
 
-// 处理函数调用
-func (i *Interpreter) evalCallExpr(call *ast.CallExpr) (any, error) {
-	// 先评估函数表达式
-	fn, err := i.eval(call.Fun)
-	if err != nil {
-		return nil, err
-	}
+// 复合赋值运算符对应的二元运算符
+var compoundOps = map[token.Token]token.Token{
+	token.ADD_ASSIGN:     token.ADD,
+	token.SUB_ASSIGN:     token.SUB,
+	token.MUL_ASSIGN:     token.MUL,
+	token.QUO_ASSIGN:     token.QUO,
+	token.REM_ASSIGN:     token.REM,
+	token.AND_ASSIGN:     token.AND,
+	token.OR_ASSIGN:      token.OR,
+	token.XOR_ASSIGN:     token.XOR,
+	token.AND_NOT_ASSIGN: token.AND_NOT,
+	token.SHL_ASSIGN:     token.SHL,
+	token.SHR_ASSIGN:     token.SHR,
+}
 
-	// 评估所有参数
-	args := make([]any, len(call.Args))
-	for idx, argExpr := range call.Args {
-		argVal, err := i.eval(argExpr)
-		if err != nil {
-			return nil, err
+// 处理复合赋值和自增自减：读取左值的当前值，计算后写回，左值可以是变量、索引表达式或选择器表达式
+// map中不存在的键（值为nil）在与数值运算时视为零
+func (i *Interpreter) compoundAssign(lhs ast.Expr, op token.Token, operand any, operandExpr ast.Expr) error {
+	return i.update(lhs, func(current any) (any, error) {
+		if current == nil && isNumber(operand) {
+			current = reflect.Zero(reflect.TypeOf(operand)).Interface()
 		}
-		args[idx] = argVal
-	}
+		return i.applyBinary(op, current, operand, lhs, operandExpr)
+	})
+}
 
-	// 根据函数类型进行不同的处理
-	switch fn := fn.(type) {
-	case func(...any) (any, error):
-		// 闭包函数
-		return fn(args...)
-	case reflect.Value:
-		// 内置函数
-		reflectArgs := make([]reflect.Value, len(args))
-		fnType := fn.Type()
-		for idx, arg := range args {
-			if arg == nil {
-				var paramType reflect.Type
-				if fnType.IsVariadic() && idx >= fnType.NumIn()-1 {
-					paramType = fnType.In(fnType.NumIn() - 1).Elem()
-				} else {
-					paramType = fnType.In(idx)
-				}
-				reflectArgs[idx] = reflect.Zero(paramType)
-			} else {
-				reflectArgs[idx] = reflect.ValueOf(arg)
-			}
+// 读取左值的当前值，用 fn 计算新值后写回
+// 左值中的容器、索引和指针只计算一次，如 xs[next()] += 1 中的 next() 只调用一次
+func (i *Interpreter) update(lhs ast.Expr, fn func(current any) (any, error)) error {
+	var container, index any
+	var err error
+	switch l := lhs.(type) {
+	case *ast.ParenExpr:
+		return i.update(l.X, fn)
+	case *ast.IndexExpr:
+		if container, err = i.eval(l.X); err != nil {
+			return err
 		}
-		results := fn.Call(reflectArgs)
-		if len(results) == 0 {
-			return nil, nil
+		if index, err = i.eval(l.Index); err != nil {
+			return err
 		}
-		return results[0].Interface(), nil
-	case *Function:
-		// 用户定义的函数
-		// 创建新的作用域
-		prevScope := i.scope
-		newScope := &Scope{
-			parent: prevScope,
+	case *ast.SelectorExpr:
+		if container, err = i.eval(l.X); err != nil {
+			return err
 		}
-		i.scope = newScope
-		defer func() { i.scope = prevScope }()
-
-		// 处理参数
-		if len(args) != len(fn.params) {
-			return nil, fmt.Errorf("参数数量不匹配")
+	case *ast.StarExpr:
+		if container, err = i.eval(l.X); err != nil {
+			return err
 		}
+	}
 
-		// 绑定参数到新作用域
-		for idx, param := range fn.params {
-			newScope.Store(param.Names[0].Name, args[idx])
+	var current any
+	switch l := lhs.(type) {
+	case *ast.IndexExpr:
+		if current, err = indexValue(container, index); err != nil {
+			return i.atPos(err, l.Pos())
+		}
+	case *ast.SelectorExpr:
+		current, err = i.selectValue(l, container)
+	case *ast.StarExpr:
+		var v reflect.Value
+		if v, err = i.deref(l, container); err == nil {
+			current = v.Interface()
 		}
-
-		// 执行函数体
-		return i.eval(fn.body)
 	default:
-		// 使用反射处理其他类型的函数
-		fnValue := reflect.ValueOf(fn)
-		if fnValue.Kind() != reflect.Func {
-			fmt.Printf("warn: 不是可调用的函数: %T \n", fn)
-			return nil, nil
-			//return nil, fmt.Errorf("不是可调用的函数: %T", fn)
+		current, err = i.eval(lhs)
+	}
+	if err != nil {
+		return err
+	}
+
+	value, err := fn(current)
+	if err != nil {
+		return err
+	}
+	switch l := lhs.(type) {
+	case *ast.IndexExpr:
+		return i.storeIndex(l, container, index, value)
+	case *ast.SelectorExpr:
+		return i.storeField(l, container, value)
+	case *ast.StarExpr:
+		return i.storePointee(l, container, value)
+	}
+	return i.assign(lhs, value, false)
+}
+
+// 将值赋给左值表达式，define 为 true 时在当前作用域中定义变量
+func (i *Interpreter) assign(lhs ast.Expr, value any, define bool) error {
+	switch l := lhs.(type) {
+	case *ast.Ident:
+		if define {
+			if i.scope.isConst(l.Name) {
+				return fmt.Errorf("常量 %s 不能重新声明为变量", l.Name)
+			}
+			// 同一作用域中已经存在的变量（如 a, err := f() 中的 err）保持原来的存储
+			if current, ok := i.scope.Load(l.Name); ok {
+				if box, ok := current.(*varBox); ok {
+					return box.set(value)
+				}
+			}
+			i.scope.Store(l.Name, value)
+		} else {
+			return setVariable(i.scope, l.Name, value)
+		}
+	case *ast.IndexExpr:
+		// 获取容器
+		container, err := i.eval(l.X)
+		if err != nil {
+			return err
+		}
+
+		// 获取索引
+		index, err := i.eval(l.Index)
+		if err != nil {
+			return err
 		}
+		return i.storeIndex(l, container, index, value)
+	case *ast.SelectorExpr:
+		// 获取容器
+		container, err := i.eval(l.X)
+		if err != nil {
+			return err
+		}
+		return i.storeField(l, container, value)
+	case *ast.StarExpr:
+		return i.assignStar(l, value)
+	case *ast.ParenExpr:
+		return i.assign(l.X, value, define)
+	default:
+		return fmt.Errorf("不支持的赋值目标类型: %T", l)
+	}
+	return nil
+}
 
-		// 准备参数
-		fnType := fnValue.Type()
-		if fnType.IsVariadic() {
-			// 处理可变参数函数
-			if len(args) < fnType.NumIn()-1 {
-				return nil, fmt.Errorf("参数数量不足: 至少需要 %d 个参数, 得到 %d 个", fnType.NumIn()-1, len(args))
+// 对已求值的容器和索引赋值
+func (i *Interpreter) storeIndex(l *ast.IndexExpr, container, index, value any) error {
+	// 根据容器类型进行赋值
+	switch c := container.(type) {
+	case map[any]any:
+		c[index] = value
+	case map[string]any:
+		if strKey, ok := index.(string); ok {
+			c[strKey] = value
+		} else {
+			return fmt.Errorf("map键必须是字符串类型")
+		}
+	case []any:
+		if intIndex, ok := index.(int); ok {
+			if intIndex < 0 || intIndex >= len(c) {
+				return i.atPos(indexOutOfRange(intIndex, len(c)), l.Pos())
 			}
-		} else if fnType.NumIn() != len(args) {
-			return nil, fmt.Errorf("参数数量不匹配: 期望 %d, 得到 %d", fnType.NumIn(), len(args))
+			c[intIndex] = value
+		} else {
+			return fmt.Errorf("slice索引必须是整数")
 		}
+	default:
+		return i.assignIndex(l, container, index, value)
+	}
+	return nil
+}
 
-		callArgs := make([]reflect.Value, len(args))
-		for i := 0; i < len(args); i++ {
-			arg := args[i]
-			var paramType reflect.Type
-			if fnType.IsVariadic() && i >= fnType.NumIn()-1 {
-				// 对于可变参数部分，使用可变参数的类型
-				paramType = fnType.In(fnType.NumIn() - 1).Elem()
-			} else {
-				paramType = fnType.In(i)
+// 对已求值的对象的字段赋值
+func (i *Interpreter) storeField(l *ast.SelectorExpr, container, value any) error {
+	// 根据容器类型进行赋值
+	switch c := container.(type) {
+	case map[any]any:
+		c[l.Sel.Name] = value
+	case map[string]any:
+		c[l.Sel.Name] = value
+	default:
+		if v := reflect.ValueOf(container); v.Kind() == reflect.Struct {
+			// 结构体值不可寻址，修改副本后写回原来的位置
+			ptr := reflect.New(v.Type())
+			ptr.Elem().Set(v)
+			if err := globalReflectCache.set(ptr.Interface(), l.Sel.Name, value); err != nil {
+				return err
 			}
+			return i.assign(l.X, ptr.Elem().Interface(), false)
+		}
+		// 使用反射缓存处理结构体字段赋值
+		if err := globalReflectCache.set(container, l.Sel.Name, value); err != nil {
+			return err
+		}
+		// item := globalReflectCache.analyze(container)
+		// if fieldInfo, ok := item.fields[l.Sel.Name]; ok {
+		// 	v := reflect.ValueOf(container)
+		// 	var base unsafe.Pointer
+		// 	if v.Kind() == reflect.Ptr {
+		// 		base = unsafe.Pointer(v.Pointer())
+		// 	} else {
+		// 		// 如果不是指针，创建一个临时指针
+		// 		ptr := reflect.New(v.Type())
+		// 		ptr.Elem().Set(v)
+		// 		base = unsafe.Pointer(ptr.Pointer())
+		// 		// 注意：这种情况下修改不会影响原始值，因为我们修改的是副本
+		// 		// 可能需要返回错误或警告
+		// 		return fmt.Errorf("无法修改非指针结构体的字段: %s", l.Sel.Name)
+		// 	}
+
+		// 	// 获取字段的指针
+		// 	ptr := unsafe.Pointer(uintptr(base) + fieldInfo.offset)
+		// 	field := reflect.NewAt(fieldInfo.typ, ptr).Elem()
+
+		// 	if !field.CanSet() {
+		// 		return fmt.Errorf("结构体字段 %s 不可写入（可能是未导出字段）", l.Sel.Name)
+		// 	}
+
+		// 	// 尝试设置字段值
+		// 	fieldValue := reflect.ValueOf(value)
+		// 	if fieldValue.Type().AssignableTo(field.Type()) {
+		// 		field.Set(fieldValue)
+		// 		return values, nil
+		// 	}
+
+		// 	return fmt.Errorf("类型不匹配：无法将 %T 赋值给 %s", value, field.Type())
+		// }
+		// return fmt.Errorf("不支持的选择器赋值操作: %T 没有字段 %s", container, l.Sel.Name)
+	}
+	return nil
+}
 
-			if arg == nil {
-				callArgs[i] = reflect.Zero(paramType)
-			} else {
-				argValue := reflect.ValueOf(arg)
-				// 如果需要类型转换且可以转换，则进行转换
-				if argValue.Type().ConvertibleTo(paramType) {
-					callArgs[i] = argValue.Convert(paramType)
-				} else {
-					callArgs[i] = argValue
-				}
+// 从 scope 开始查找变量并赋值，找不到变量时什么也不做
+func setVariable(scope *Scope, name string, value any) error {
+	for ; scope != nil; scope = scope.parent {
+		if current, ok := scope.Load(name); ok {
+			if scope.isConst(name) {
+				return fmt.Errorf("无法给常量 %s 赋值", name)
+			}
+			if box, ok := current.(*varBox); ok {
+				return box.set(value)
 			}
+			scope.Store(name, value)
+			return nil
 		}
+	}
+	return nil
+}
 
-		// 调用函数
-		results := fnValue.Call(callArgs)
-		if len(results) == 0 {
-			return nil, nil
+// 通过反射对宿主的map、slice和数组进行索引赋值
+func (i *Interpreter) assignIndex(l *ast.IndexExpr, container any, index any, value any) error {
+	v := reflect.ValueOf(container)
+	switch v.Kind() {
+	case reflect.Map:
+		key, err := toValue(index, v.Type().Key())
+		if err != nil {
+			return err
+		}
+		elem, err := toValue(value, v.Type().Elem())
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	case reflect.Slice, reflect.Array:
+		idx, ok := index.(int)
+		if !ok {
+			return fmt.Errorf("slice索引必须是整数")
 		}
-		return results[0].Interface(), nil
+		if idx < 0 || idx >= v.Len() {
+			return i.atPos(indexOutOfRange(idx, v.Len()), l.Pos())
+		}
+		elem, err := toValue(value, v.Type().Elem())
+		if err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Slice {
+			v.Index(idx).Set(elem)
+			return nil
+		}
+		// 数组是值类型，修改副本后写回原来的位置
+		array := reflect.New(v.Type()).Elem()
+		array.Set(v)
+		array.Index(idx).Set(elem)
+		return i.assign(l.X, array.Interface(), false)
+	}
+	return fmt.Errorf("不支持的索引赋值操作: %T", container)
+}
 
-		// return nil, fmt.Errorf("不是可调用的函数: %T", fn)
+// 将宿主函数的返回值转换为脚本中的值，多个返回值时为 tuple
+func resultsValue(results []reflect.Value) any {
+	switch len(results) {
+	case 0:
+		return nil
+	case 1:
+		return results[0].Interface()
+	}
+	values := make(tuple, len(results))
+	for idx, result := range results {
+		values[idx] = result.Interface()
 	}
+	return values
 }
 
-// 处理二元表达式
-func (i *Interpreter) evalBinaryExpr(expr *ast.BinaryExpr) (any, error) {
-	left, err := i.eval(expr.X)
-	if err != nil {
+// 将多返回值展开为n个值，数量不匹配时返回错误
+func expandValues(values []any, n int) ([]any, error) {
+	if len(values) == 1 && n != 1 {
+		if t, ok := values[0].(tuple); ok {
+			values = t
+		}
+	}
+	if len(values) != n {
+		return nil, fmt.Errorf("赋值数量不匹配: %d 个变量, 但有 %d 个值", n, len(values))
+	}
+	for _, value := range values {
+		if t, ok := value.(tuple); ok {
+			return nil, fmt.Errorf("多返回值 (%d 个值) 不能用于单值上下文", len(t))
+		}
+	}
+	return values, nil
+}
+
+// 计算赋值右侧的表达式，并展开为n个值
+// 支持多返回值 a, b := f() 以及 map 的 comma-ok 形式 v, ok := m[k]
+func (i *Interpreter) evalValues(exprs []ast.Expr, n int) ([]any, error) {
+	if len(exprs) == 1 && n == 2 {
+		switch expr := exprs[0].(type) {
+		case *ast.IndexExpr:
+			return i.evalIndexOk(expr)
+		case *ast.TypeAssertExpr:
+			return i.evalTypeAssertOk(expr)
+		case *ast.UnaryExpr:
+			if expr.Op == token.ARROW {
+				return i.evalRecvOk(expr)
+			}
+		}
+	}
+	values := make([]any, len(exprs))
+	for idx, expr := range exprs {
+		val, err := i.eval(expr)
+		if err != nil {
+			return nil, err
+		}
+		values[idx] = val
+	}
+	return expandValues(values, n)
+}
+
+// 处理函数调用
+func (i *Interpreter) evalCallExpr(call *ast.CallExpr) (any, error) {
+	// 类型转换，如 int(x)、time.Duration(n)
+	if typ, ok := i.conversionType(call.Fun); ok {
+		return i.evalConversion(call, typ)
+	}
+
+	// 先评估函数表达式
+	var fn any
+	var err error
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+		value, done, err := i.methodCallee(call, sel)
+		if err != nil || done {
+			return value, err
+		}
+		fn = value
+	} else if fn, err = i.eval(call.Fun); err != nil {
 		return nil, err
 	}
 
-	right, err := i.eval(expr.Y)
+	// 评估所有参数
+	args, err := i.evalCallArgs(call)
 	if err != nil {
 		return nil, err
 	}
 
-	switch expr.Op {
-	case token.ADD:
-		return add(left, right)
-	case token.SUB:
-		return sub(left, right)
-	case token.MUL:
-		return mul(left, right)
-	case token.QUO:
-		return div(left, right)
-	case token.LSS:
-		return lessThan(left, right)
-	case token.GTR:
-		return greaterThan(left, right)
-	case token.LEQ:
-		less, err := lessThan(left, right)
+	return i.callFunction(call, fn, args)
+}
+
+// 求值方法调用 sel.X.Name(...) 调用的函数
+// 在结构体值上调用脚本类型的指针接收器的方法时，由 callPointerMethod 直接完成调用，返回调用的结果，done 为 true
+func (i *Interpreter) methodCallee(call *ast.CallExpr, sel *ast.SelectorExpr) (value any, done bool, err error) {
+	container, err := i.methodReceiver(sel)
+	if err != nil {
+		return nil, false, err
+	}
+	if method, copied, writeBack := i.methodValue(container, sel.Sel.Name); writeBack {
+		value, err = i.callPointerMethod(call, sel, method, copied)
+		return value, true, err
+	}
+	value, err = i.selectValue(sel, container)
+	return value, false, err
+}
+
+// 在结构体值上调用指针接收器的方法：能取得地址时使用原来的值的地址，
+// 否则（如 []any 中的元素）在副本上调用，调用后将副本写回原来的位置
+func (i *Interpreter) callPointerMethod(call *ast.CallExpr, sel *ast.SelectorExpr, method any, copied reflect.Value) (any, error) {
+	// 可以取得地址时（如变量、结构体指针的字段），直接在原来的值上调用
+	if addr, err := i.addressOf(sel.X); err == nil && addr.Type() == copied.Type() {
+		method, _, _ := i.methodValue(addr.Addr().Interface(), sel.Sel.Name)
+		args, err := i.evalCallArgs(call)
 		if err != nil {
 			return nil, err
 		}
-		equal, err := equal(left, right)
+		return i.callFunction(call, method, args)
+	}
+	if !isAddressable(sel.X) {
+		return nil, fmt.Errorf("无法在不可寻址的值上调用指针接收器的方法 %s", sel.Sel.Name)
+	}
+	args, err := i.evalCallArgs(call)
+	if err != nil {
+		return nil, err
+	}
+	result, err := i.callFunction(call, method, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.assign(sel.X, copied.Interface(), false); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// 评估函数调用的参数列表
+// 唯一的参数是多返回值的函数调用时，展开为多个参数，如 f(g())
+func (i *Interpreter) evalArgs(exprs []ast.Expr) ([]any, error) {
+	if len(exprs) == 1 {
+		argVal, err := i.eval(exprs[0])
 		if err != nil {
 			return nil, err
 		}
-		return less || equal, nil
-	case token.GEQ:
-		greater, err := greaterThan(left, right)
+		if t, ok := argVal.(tuple); ok {
+			return t, nil
+		}
+		return []any{argVal}, nil
+	}
+	args := make([]any, len(exprs))
+	for idx, argExpr := range exprs {
+		argVal, err := i.eval(argExpr)
 		if err != nil {
 			return nil, err
 		}
-		equal, err := equal(left, right)
+		args[idx] = argVal
+	}
+	return args, nil
+}
+
+// 评估函数调用的参数，f(xs...) 形式的调用将最后一个参数展开为可变参数
+func (i *Interpreter) evalCallArgs(call *ast.CallExpr) ([]any, error) {
+	args, err := i.evalArgs(call.Args)
+	if err != nil || !call.Ellipsis.IsValid() {
+		return args, err
+	}
+	last := args[len(args)-1]
+	if _, ok := last.(string); ok {
+		return nil, fmt.Errorf("只能展开切片，得到: string")
+	}
+	rest, err := spreadValues(last)
+	if err != nil {
+		return nil, err
+	}
+	return append(args[:len(args)-1:len(args)-1], rest...), nil
+}
+
+// 使用已求值的参数调用函数
+func (i *Interpreter) callFunction(call *ast.CallExpr, fn any, args []any) (any, error) {
+	// 根据函数类型进行不同的处理
+	switch fn := fn.(type) {
+	case func(...any) (any, error):
+		// 闭包函数，传入调用者的帧
+		return fn(withCaller(i.frame, args)...)
+	case reflect.Value:
+		// 内置函数
+		return i.callReflect(call, fn, args)
+	case *genericFunc:
+		// 泛型函数，根据参数推断类型参数
+		return i.callGeneric(call, fn, args)
+	case *Function:
+		// 用户定义的函数，在新的作用域中执行
+		newScope := &Scope{
+			parent: i.scope,
+		}
+
+		// 绑定参数到新作用域
+		if err := bindParams(newScope, fn.params, args); err != nil {
+			return nil, err
+		}
+
+		// 执行函数体
+		return i.withScope(newScope).eval(fn.body)
+	default:
+		// 使用反射处理其他类型的函数
+		fnValue := reflect.ValueOf(fn)
+		if fnValue.Kind() != reflect.Func {
+			fmt.Printf("warn: 不是可调用的函数: %T \n", fn)
+			return nil, nil
+			//return nil, fmt.Errorf("不是可调用的函数: %T", fn)
+		}
+
+		return i.callReflect(call, fnValue, args)
+
+		// return nil, fmt.Errorf("不是可调用的函数: %T", fn)
+	}
+}
+
+// 通过反射调用宿主函数，参数按 hostArg 的规则转换为函数的参数类型
+func (i *Interpreter) callReflect(call *ast.CallExpr, fnValue reflect.Value, args []any) (any, error) {
+	fnType := fnValue.Type()
+	spread := call != nil && call.Ellipsis.IsValid()
+	if spread && !fnType.IsVariadic() {
+		return nil, fmt.Errorf("不能将 ... 用于非可变参数的函数")
+	}
+	if fnType.IsVariadic() {
+		// 处理可变参数函数
+		if len(args) < fnType.NumIn()-1 {
+			return nil, fmt.Errorf("参数数量不足: 至少需要 %d 个参数, 得到 %d 个", fnType.NumIn()-1, len(args))
+		}
+	} else if fnType.NumIn() != len(args) {
+		return nil, fmt.Errorf("参数数量不匹配: 期望 %d, 得到 %d", fnType.NumIn(), len(args))
+	}
+
+	callArgs := make([]reflect.Value, len(args))
+	for idx, arg := range args {
+		var paramType reflect.Type
+		if fnType.IsVariadic() && idx >= fnType.NumIn()-1 {
+			// 对于可变参数部分，使用可变参数的类型
+			paramType = fnType.In(fnType.NumIn() - 1).Elem()
+		} else {
+			paramType = fnType.In(idx)
+		}
+		// 参数与调用表达式一一对应时，可以获取参数的地址
+		var expr ast.Expr
+		if call != nil && !spread && len(call.Args) == len(args) {
+			expr = call.Args[idx]
+		}
+		value, err := i.hostArg(expr, arg, paramType)
 		if err != nil {
 			return nil, err
 		}
-		return greater || equal, nil
-	case token.EQL:
-		return equal(left, right)
+		callArgs[idx] = value
+	}
+
+	// 调用函数
+	results, err := i.callHost(call, fnValue, callArgs)
+	if err != nil {
+		return nil, err
+	}
+	return resultsValue(results), nil
+}
+
+// 处理二元表达式
+func (i *Interpreter) evalBinaryExpr(expr *ast.BinaryExpr) (any, error) {
+	left, err := i.eval(expr.X)
+	if err != nil {
+		return nil, err
+	}
+	if done, value := shortCircuit(expr.Op, left); done {
+		return value, nil
+	}
+
+	right, err := i.eval(expr.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.applyBinary(expr.Op, left, right, expr.X, expr.Y)
+}
+
+// 计算 x op y，left、right 是 x、y 的值
+// 操作数是不同的数值类型时，根据表达式判断操作数是否是无类型常量
+func (i *Interpreter) applyBinary(op token.Token, left, right any, x, y ast.Expr) (any, error) {
+	value, err := binaryOp(op, left, right, i.untypedOperands(left, right, x, y))
+	if err != nil {
+		return nil, i.atPos(err, x.Pos())
+	}
+	return value, nil
+}
+
+// 类型不同的两个数值中哪些是无类型常量，x、y 是操作数的表达式
+func (i *Interpreter) untypedOperands(left, right any, x, y ast.Expr) untypedOperands {
+	var untyped untypedOperands
+	if isNumber(left) && isNumber(right) && reflect.TypeOf(left) != reflect.TypeOf(right) {
+		if i.isUntyped(x) {
+			untyped |= untypedLeft
+		}
+		if i.isUntyped(y) {
+			untyped |= untypedRight
+		}
+	}
+	return untyped
+}
+
+// 表达式是否是无类型常量：数值和字符字面量、未声明类型的常量，以及只由它们组成的表达式
+func (i *Interpreter) isUntyped(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return e.Kind != token.STRING
+	case *ast.ParenExpr:
+		return i.isUntyped(e.X)
+	case *ast.UnaryExpr:
+		return i.isUntyped(e.X)
+	case *ast.BinaryExpr:
+		return i.isUntyped(e.X) && i.isUntyped(e.Y)
+	case *ast.Ident:
+		return i.isUntypedConst(e.Name)
+	}
+	return false
+}
+
+// 计算二元运算，复合赋值（如 +=）与二元表达式共用，untyped 标记了哪些操作数是无类型常量
+func binaryOp(op token.Token, left, right any, untyped untypedOperands) (any, error) {
+	switch op {
+	case token.ADD:
+		return add(left, right, untyped)
+	case token.SUB:
+		return arith(token.SUB, left, right, untyped)
+	case token.MUL:
+		return arith(token.MUL, left, right, untyped)
+	case token.QUO:
+		return arith(token.QUO, left, right, untyped)
+	case token.LSS, token.GTR, token.LEQ, token.GEQ, token.EQL, token.NEQ:
+		return comparison(op, left, right, untyped)
 	case token.REM:
-		return mod(left, right)
+		return mod(left, right, untyped)
+	case token.AND, token.OR, token.XOR, token.AND_NOT:
+		return arith(op, left, right, untyped)
+	case token.SHL, token.SHR:
+		return shift(op, left, right)
 	case token.LAND:
 		return and(left, right)
 	case token.LOR:
 		return or(left, right)
-	case token.NEQ: // !=
-		equal, err := equal(left, right)
-		if err != nil {
-			return nil, err
-		}
-		return !equal, nil
 	default:
-		return nil, fmt.Errorf("不支持的运算符: %s", expr.Op)
+		return nil, fmt.Errorf("不支持的运算符: %s", op)
 	}
 }
 
@@ -942,6 +1682,9 @@ func toBool(val any) bool {
 	case string:
 		return v != ""
 	default:
+		if isNumber(v) {
+			return !reflect.ValueOf(v).IsZero()
+		}
 		if reflect.TypeOf(v).Kind() == reflect.Map || reflect.TypeOf(v).Kind() == reflect.Slice {
 			return reflect.ValueOf(v).Len() > 0
 		}
@@ -950,214 +1693,108 @@ func toBool(val any) bool {
 }
 
 // 算术运算实现
-func add(a, b any) (any, error) {
+// 数值按 numericType 的规则运算；字符串与字符串、数值或 nil 相加时拼接为字符串
+func add(a, b any, untyped untypedOperands) (any, error) {
 	if a == nil {
 		a = ""
 	}
 	if b == nil {
 		b = ""
 	}
-	switch a := a.(type) {
-	case int:
-		switch b := b.(type) {
-		case int:
-			return a + b, nil
-		case float64:
-			return float64(a) + b, nil
-		case string:
-			return fmt.Sprintf("%d%s", a, b), nil
-		}
-	case float64:
-		switch b := b.(type) {
-		case int:
-			return a + float64(b), nil
-		case float64:
-			return a + b, nil
-		case string:
-			return fmt.Sprintf("%f%s", a, b), nil
-		}
-	case string:
-		switch b := b.(type) {
-		case int:
-			return fmt.Sprintf("%s%d", a, b), nil
-		case float64:
-			return fmt.Sprintf("%s%f", a, b), nil
-		case string:
-			return a + b, nil
-		}
+	aStr, aIsStr := a.(string)
+	bStr, bIsStr := b.(string)
+	switch {
+	case aIsStr && bIsStr:
+		return aStr + bStr, nil
+	case aIsStr && isNumber(b):
+		return aStr + formatNumber(b), nil
+	case bIsStr && isNumber(a):
+		return formatNumber(a) + bStr, nil
+	case isNumber(a) && isNumber(b):
+		return arith(token.ADD, a, b, untyped)
 	}
 	return nil, fmt.Errorf("类型不匹配: %T + %T", a, b)
 }
 
-// 减法运算
-func sub(a, b any) (any, error) {
-	switch a := a.(type) {
-	case int:
-		switch b := b.(type) {
-		case int:
-			return a - b, nil
-		case float64:
-			return float64(a) - b, nil
-		}
-	case float64:
-		switch b := b.(type) {
-		case int:
-			return a - float64(b), nil
-		case float64:
-			return a - b, nil
-		}
-	}
-	return nil, fmt.Errorf("无效操作: %T - %T", a, b)
-}
-
-// 乘法运算
-func mul(a, b any) (any, error) {
-	switch a := a.(type) {
-	case int:
-		switch b := b.(type) {
-		case int:
-			return a * b, nil
-		case float64:
-			return float64(a) * b, nil
-		}
-	case float64:
-		switch b := b.(type) {
-		case int:
-			return a * float64(b), nil
-		case float64:
-			return a * b, nil
-		}
-	}
-	return nil, fmt.Errorf("无效操作: %T * %T", a, b)
-}
-
-// 除法运算
-func div(a, b any) (any, error) {
-	switch a := a.(type) {
-	case int:
-		switch b := b.(type) {
-		case int:
-			if b == 0 {
-				return nil, fmt.Errorf("除以零错误")
-			}
-			return a / b, nil
-		case float64:
-			if b == 0 {
-				return nil, fmt.Errorf("除以零错误")
-			}
-			return float64(a) / b, nil
-		}
-	case float64:
-		switch b := b.(type) {
-		case int:
-			if b == 0 {
-				return nil, fmt.Errorf("除以零错误")
-			}
-			return a / float64(b), nil
-		case float64:
-			if b == 0 {
-				return nil, fmt.Errorf("除以零错误")
-			}
-			return a / b, nil
-		}
-	}
-	return nil, fmt.Errorf("无效操作: %T / %T", a, b)
-}
-
-// 比较运算
-func lessThan(a, b any) (bool, error) {
-	switch a := a.(type) {
-	case int:
-		switch b := b.(type) {
-		case int:
-			return a < b, nil
-		case float64:
-			return float64(a) < b, nil
-		}
-	case float64:
-		switch b := b.(type) {
-		case int:
-			return a < float64(b), nil
-		case float64:
-			return a < b, nil
-		}
-	case string:
-		if bStr, ok := b.(string); ok {
-			return a < bStr, nil
-		}
+// 与字符串拼接时数值的格式，rune（int32，如 'a'）为对应的字符，其它整数为 %d，浮点数为 %f
+func formatNumber(v any) string {
+	if r, ok := v.(rune); ok {
+		return string(r)
 	}
-	return false, fmt.Errorf("类型不匹配比较: %T < %T", a, b)
-}
-
-func greaterThan(a, b any) (bool, error) {
-	switch a := a.(type) {
-	case int:
-		switch b := b.(type) {
-		case int:
-			return a > b, nil
-		case float64:
-			return float64(a) > b, nil
-		}
-	case float64:
-		switch b := b.(type) {
-		case int:
-			return a > float64(b), nil
-		case float64:
-			return a > b, nil
-		}
-	case string:
-		if bStr, ok := b.(string); ok {
-			return a > bStr, nil
-		}
+	if isFloatKind(reflect.TypeOf(v).Kind()) {
+		return fmt.Sprintf("%f", v)
 	}
-	return false, fmt.Errorf("类型不匹配比较: %T > %T", a, b)
+	return fmt.Sprintf("%d", v)
 }
 
-func equal(a, b any) (bool, error) {
-	switch a := a.(type) {
-	case int:
-		switch b := b.(type) {
-		case int:
-			return a == b, nil
-		case float64:
-			return float64(a) == b, nil
-		}
-	case float64:
-		switch b := b.(type) {
-		case int:
-			return a == float64(b), nil
-		case float64:
-			return a == b, nil
-		}
-	case string:
-		if bStr, ok := b.(string); ok {
-			return a == bStr, nil
+// 比较运算 ==、!=、<、<=、>、>=，untyped 标记了哪些操作数是无类型常量
+// 与go一样，NaN 与任何值（包括它自己）比较时只有 != 成立
+func comparison(op token.Token, a, b any, untyped untypedOperands) (bool, error) {
+	if op == token.EQL || op == token.NEQ {
+		eq, err := equal(a, b, untyped)
+		if err != nil {
+			return false, err
 		}
-	case bool:
-		if bBool, ok := b.(bool); ok {
-			return a == bBool, nil
+		return eq == (op == token.EQL), nil
+	}
+	if aStr, ok := a.(string); ok {
+		if bStr, ok := b.(string); ok {
+			return ordered(op, aStr, bStr), nil
 		}
-	case nil:
-		return b == nil, nil
+	}
+	if isNumber(a) && isNumber(b) {
+		return compareNumbers(op, a, b, untyped)
+	}
+	return false, fmt.Errorf("类型不匹配比较: %T 和 %T", a, b)
+}
+
+func equal(a, b any, untyped untypedOperands) (bool, error) {
+	if a == nil || b == nil {
+		// 与nil比较时，值为nil的切片、map、指针等也等于nil
+		return isNil(a) && isNil(b), nil
+	}
+	if isNumber(a) && isNumber(b) {
+		return compareNumbers(token.EQL, a, b, untyped)
+	}
+	aType := reflect.TypeOf(a)
+	if aType == reflect.TypeOf(b) && aType.Comparable() {
+		return a == b, nil
 	}
 	return false, nil // 类型不同直接返回false
 }
 
-// 取模运算（需要单独处理）
-func mod(a, b any) (any, error) {
-	switch a := a.(type) {
-	case int:
-		switch b := b.(type) {
-		case int:
-			if b == 0 {
-				return nil, fmt.Errorf("取模运算除数为零")
-			}
-			return a % b, nil
-		}
+// 值是否是nil，包括值为nil的切片、map、指针、函数和channel
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// 取模运算，只支持整数
+func mod(a, b any, untyped untypedOperands) (any, error) {
+	if isNumber(a) && isNumber(b) && !isFloatKind(reflect.TypeOf(a).Kind()) && !isFloatKind(reflect.TypeOf(b).Kind()) {
+		return arith(token.REM, a, b, untyped)
 	}
 	return nil, fmt.Errorf("无效操作: %T %% %T", a, b)
 }
 
+// && 和 || 短路求值，左侧的值已经决定结果时不再计算右侧（如 p != nil && *p > 0）
+func shortCircuit(op token.Token, left any) (done bool, value any) {
+	switch op {
+	case token.LAND:
+		return !toBool(left), false
+	case token.LOR:
+		return toBool(left), true
+	}
+	return false, nil
+}
+
 func and(a, b any) (any, error) {
 	left := toBool(a)
 	if !left {
@@ -1178,84 +1815,57 @@ func or(a, b any) (any, error) {
 
 // 处理return语句的函数
 func (i *Interpreter) evalReturnStmt(ret *ast.ReturnStmt) (any, error) {
-	if len(ret.Results) == 0 {
-		// 空的return语句，需要返回命名返回值的当前状态
-		// 这里返回一个特殊标记，让调用者知道这是一个空return
-		return emptyReturn, nil
+	// 空的return语句返回命名返回值的当前状态，由 evalFuncBody 处理
+	values := make(returnValues, len(ret.Results))
+	for idx, expr := range ret.Results {
+		val, err := i.eval(expr)
+		if err != nil {
+			return nil, err
+		}
+		values[idx] = val
 	}
-	// 目前只处理单个返回值
-	return i.eval(ret.Results[0])
+	return values, nil
 }
 
 // 处理自增自减语句
 func (i *Interpreter) evalIncDecStmt(stmt *ast.IncDecStmt) (any, error) {
-	// 获取操作数
-	ident, ok := stmt.X.(*ast.Ident)
-	if !ok {
-		return nil, fmt.Errorf("自增自减操作只支持变量")
-	}
-
-	// 获取当前值
-	currentVal, err := i.evalIdent(ident)
-	if err != nil {
-		return nil, err
-	}
-
-	// 根据操作符计算新值
-	var newVal any
-	switch stmt.Tok {
-	case token.INC: // ++
-		newVal, err = add(currentVal, 1)
-	case token.DEC: // --
-		newVal, err = sub(currentVal, 1)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	// 更新变量值
-	currentScope := i.scope
-	for currentScope != nil {
-		if _, ok := currentScope.Load(ident.Name); ok {
-			currentScope.Store(ident.Name, newVal)
-			break
-		}
-		currentScope = currentScope.parent
+	op := token.ADD
+	if stmt.Tok == token.DEC {
+		op = token.SUB
 	}
-
-	return newVal, nil
+	return nil, i.compoundAssign(stmt.X, op, 1, incDecOperand)
 }
 
+// 自增自减的操作数 1，是无类型常量
+var incDecOperand = &ast.BasicLit{Kind: token.INT, Value: "1"}
+
 // 新的处理函数字面量的方法
 func (i *Interpreter) evalFuncLit(fn *ast.FuncLit) (any, error) {
+	return i.newFunction(fn.Type, fn.Body), nil
+}
+
+// 根据函数签名和函数体创建脚本函数，函数字面量和顶层函数声明共用
+func (i *Interpreter) newFunction(fnType *ast.FuncType, body *ast.BlockStmt) func(args ...any) (any, error) {
 	// 创建一个Function对象来存储函数信息
 	function := &Function{
-		params: fn.Type.Params.List,
-		body:   fn.Body,
+		params: fnType.Params.List,
+		body:   body,
 	}
 
 	// 处理返回值参数
-	if fn.Type.Results != nil {
-		function.results = fn.Type.Results.List
+	if fnType.Results != nil {
+		function.results = fnType.Results.List
 	}
 
-	// 返回一个闭包函数
+	// 返回一个闭包函数，函数体在定义函数时的作用域中执行
 	return func(args ...any) (any, error) {
-		// 创建新的作用域
-		prevScope := i.scope
-		newScope := &Scope{
-			parent: prevScope,
-		}
-		i.scope = newScope
-		defer func() { i.scope = prevScope }()
+		caller, args := callerOf(args)
+		// 创建新的作用域，BackendClosure 编译时确定了函数体的变量布局
+		scope := newScope(i.scope, i.layouts[body])
 
 		// 绑定参数
-		if len(args) != len(function.params) {
-			return nil, fmt.Errorf("参数数量不匹配")
-		}
-
-		for idx, param := range function.params {
-			newScope.Store(param.Names[0].Name, args[idx])
+		if err := bindParams(scope, function.params, args); err != nil {
+			return nil, err
 		}
 
 		// 初始化命名返回值
@@ -1267,37 +1877,62 @@ func (i *Interpreter) evalFuncLit(fn *ast.FuncLit) (any, error) {
 					if err != nil {
 						return nil, fmt.Errorf("初始化返回值失败: %v", err)
 					}
-					newScope.Store(name.Name, zeroValue)
+					scope.Store(name.Name, zeroValue)
 				}
 			}
 		}
 
-		// 执行函数体
-		result, err := i.eval(function.body)
-		if err != nil {
-			return nil, err
-		}
+		// 执行函数体，退出时执行 defer
+		return i.evalFuncBody(function.body, scope, caller, function.results)
+	}
+}
 
-		// 检查是否是空return
-		if result == emptyReturn {
-			result = nil // 将空return标记置为nil
+// bindParams 将实参绑定到函数作用域中的形参，支持 func(a, b int) 形式的参数列表，
+// 最后一个参数是可变参数（...T）时，剩余的实参收集为 []any
+func bindParams(scope *Scope, params []*ast.Field, args []any) error {
+	var names []string
+	variadic := false
+	for idx, param := range params {
+		if _, ok := param.Type.(*ast.Ellipsis); ok {
+			if idx != len(params)-1 || len(param.Names) > 1 {
+				return fmt.Errorf("只有最后一个参数可以是可变参数")
+			}
+			variadic = true
+		}
+		if len(param.Names) == 0 {
+			// 省略了参数名，如 func(int)
+			names = append(names, "_")
+			continue
 		}
+		for _, name := range param.Names {
+			names = append(names, name.Name)
+		}
+	}
 
-		// 对于有命名返回值的函数，如果没有显式返回值，返回命名返回值的当前值
-		if len(function.results) > 0 {
-			for _, resultField := range function.results {
-				if len(resultField.Names) > 0 {
-					// 返回第一个命名返回值的当前值
-					name := resultField.Names[0].Name
-					if val, ok := newScope.Load(name); ok {
-						return val, nil
-					}
-				}
-			}
+	fixed := len(names)
+	if variadic {
+		fixed--
+		if len(args) < fixed {
+			return fmt.Errorf("参数数量不足: 至少需要 %d 个参数, 得到 %d 个", fixed, len(args))
 		}
+	} else if len(args) != fixed {
+		return fmt.Errorf("参数数量不匹配: 期望 %d, 得到 %d", fixed, len(args))
+	}
 
-		return result, nil
-	}, nil
+	for idx := 0; idx < fixed; idx++ {
+		if names[idx] != "_" {
+			scope.Store(names[idx], args[idx])
+		}
+	}
+	if variadic && names[fixed] != "_" {
+		// 没有可变参数时为 nil 切片，与go一致
+		var rest []any
+		if len(args) > fixed {
+			rest = append(rest, args[fixed:]...)
+		}
+		scope.Store(names[fixed], rest)
+	}
+	return nil
 }
 
 // 处理复合字面量
@@ -1320,30 +1955,95 @@ func (i *Interpreter) evalCompositeLit(lit *ast.CompositeLit) (any, error) {
 				return nil, err
 			}
 
-			// 计算值
-			val, err := i.eval(kv.Value)
+			// 计算值，值可以是省略了类型的复合字面量
+			var elemType reflect.Type
+			if c, ok := kv.Value.(*ast.CompositeLit); ok && c.Type == nil {
+				if elemType, err = i.resolveType(t.Value); err != nil {
+					return nil, err
+				}
+			}
+			val, err := i.evalElement(kv.Value, elemType)
 			if err != nil {
 				return nil, err
 			}
 
-			m[key.(string)] = val
+			strKey, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("map键必须是字符串类型，得到: %T", key)
+			}
+			m[strKey] = val
 		}
 		return m, nil
 
 	case *ast.ArrayType:
-		// 创建slice
-		var slice []any
-		for _, elt := range lit.Elts {
-			val, err := i.eval(elt)
+		// 创建slice，容量与长度相同
+		slice := make([]any, len(lit.Elts))
+		var elemType reflect.Type
+		for idx, elt := range lit.Elts {
+			if c, ok := elt.(*ast.CompositeLit); ok && c.Type == nil && elemType == nil {
+				// 省略了类型的元素，如 []Order{{ID: 1}}
+				var err error
+				if elemType, err = i.resolveType(t.Elt); err != nil {
+					return nil, err
+				}
+			}
+			val, err := i.evalElement(elt, elemType)
 			if err != nil {
 				return nil, err
 			}
-			slice = append(slice, val)
+			slice[idx] = val
 		}
 		return slice, nil
 
 	default:
-		return nil, fmt.Errorf("不支持的复合字面量类型: %T", t)
+		// 结构体等命名类型
+		typ, err := i.resolveType(lit.Type)
+		if err != nil {
+			return nil, fmt.Errorf("不支持的复合字面量类型: %T: %v", t, err)
+		}
+		return i.evalCompositeLitOf(lit, typ)
+	}
+}
+
+// 根据已解析的类型创建复合字面量的值
+func (i *Interpreter) evalCompositeLitOf(lit *ast.CompositeLit, typ reflect.Type) (any, error) {
+	switch typ.Kind() {
+	case reflect.Struct:
+		return i.newStruct(typ, lit)
+	case reflect.Slice, reflect.Array:
+		slice := make([]any, len(lit.Elts))
+		for idx, elt := range lit.Elts {
+			val, err := i.evalElement(elt, typ.Elem())
+			if err != nil {
+				return nil, err
+			}
+			slice[idx] = val
+		}
+		return slice, nil
+	case reflect.Map:
+		m := make(map[string]any)
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("map字面量必须是键值对")
+			}
+			key, err := i.eval(kv.Key)
+			if err != nil {
+				return nil, err
+			}
+			strKey, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("map键必须是字符串类型，得到: %T", key)
+			}
+			val, err := i.evalElement(kv.Value, typ.Elem())
+			if err != nil {
+				return nil, err
+			}
+			m[strKey] = val
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("不支持的复合字面量类型: %s", typeName(typ))
 	}
 }
 
@@ -1372,13 +2072,25 @@ func (i *Interpreter) evalIndexExpr(expr *ast.IndexExpr) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	if g, ok := container.(*genericFunc); ok {
+		// 泛型函数的实例化 f[int]
+		return i.instantiate(g, []ast.Expr{expr.Index})
+	}
 
 	// 计算索引值
 	index, err := i.eval(expr.Index)
 	if err != nil {
 		return nil, err
 	}
+	value, err := indexValue(container, index)
+	if err != nil {
+		return nil, i.atPos(err, expr.Pos())
+	}
+	return value, nil
+}
 
+// 从已求值的容器中取出索引对应的元素，索引越界是运行时panic
+func indexValue(container, index any) (any, error) {
 	// 根据容器类型进行不同的处理
 	switch c := container.(type) {
 	case map[any]any:
@@ -1397,7 +2109,7 @@ func (i *Interpreter) evalIndexExpr(expr *ast.IndexExpr) (any, error) {
 		switch idx := index.(type) {
 		case int:
 			if idx < 0 || idx >= len(c) {
-				return nil, fmt.Errorf("索引越界: %d", idx)
+				return nil, indexOutOfRange(idx, len(c))
 			}
 			return c[idx], nil
 		default:
@@ -1409,7 +2121,7 @@ func (i *Interpreter) evalIndexExpr(expr *ast.IndexExpr) (any, error) {
 		switch idx := index.(type) {
 		case int:
 			if idx < 0 || idx >= len(c) {
-				return nil, fmt.Errorf("索引越界: %d", idx)
+				return nil, indexOutOfRange(idx, len(c))
 			}
 			return string(c[idx]), nil
 		default:
@@ -1422,16 +2134,21 @@ func (i *Interpreter) evalIndexExpr(expr *ast.IndexExpr) (any, error) {
 		case reflect.Map:
 			// 使用map返回
 			mapValue := reflect.ValueOf(container)
-			keyValue := reflect.ValueOf(index)
+			keyValue, err := toValue(index, mapValue.Type().Key())
+			if err != nil {
+				return nil, err
+			}
 			if val := mapValue.MapIndex(keyValue); val.IsValid() {
 				return val.Interface(), nil
 			}
+			// 不存在的键返回值类型的零值
+			return reflect.Zero(mapValue.Type().Elem()).Interface(), nil
 		case reflect.Slice, reflect.Array:
 			// 使用slice返回
 			sliceValue := reflect.ValueOf(container)
 			if idx, ok := index.(int); ok {
 				if idx < 0 || idx >= sliceValue.Len() {
-					return nil, fmt.Errorf("索引越界: %d", idx)
+					return nil, indexOutOfRange(idx, sliceValue.Len())
 				}
 				return sliceValue.Index(idx).Interface(), nil
 			}
@@ -1440,6 +2157,48 @@ func (i *Interpreter) evalIndexExpr(expr *ast.IndexExpr) (any, error) {
 	}
 }
 
+func indexOutOfRange(idx, length int) error {
+	return runtimeError("index out of range [%d] with length %d", idx, length)
+}
+
+// 处理 comma-ok 形式的map索引 v, ok := m[k]
+func (i *Interpreter) evalIndexOk(expr *ast.IndexExpr) ([]any, error) {
+	container, err := i.eval(expr.X)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.eval(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := container.(type) {
+	case map[any]any:
+		val, ok := c[index]
+		return []any{val, ok}, nil
+	case map[string]any:
+		strKey, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("map键必须是字符串类型，得到: %T", index)
+		}
+		val, ok := c[strKey]
+		return []any{val, ok}, nil
+	default:
+		mapValue := reflect.ValueOf(container)
+		if mapValue.Kind() != reflect.Map {
+			return nil, fmt.Errorf("赋值数量不匹配: 2 个变量, 但有 1 个值")
+		}
+		keyValue := reflect.ValueOf(index)
+		if !keyValue.IsValid() || !keyValue.Type().AssignableTo(mapValue.Type().Key()) {
+			return nil, fmt.Errorf("map键类型不匹配: %T", index)
+		}
+		if val := mapValue.MapIndex(keyValue); val.IsValid() {
+			return []any{val.Interface(), true}, nil
+		}
+		return []any{reflect.Zero(mapValue.Type().Elem()).Interface(), false}, nil
+	}
+}
+
 // 处理选择器表达式的方法
 func (i *Interpreter) evalSelectorExpr(sel *ast.SelectorExpr) (any, error) {
 	// 计算被选择的对象
@@ -1453,6 +2212,16 @@ func (i *Interpreter) evalSelectorExpr(sel *ast.SelectorExpr) (any, error) {
 		return nil, nil
 	}
 
+	return i.selectValue(sel, container)
+}
+
+// 从已求值的对象中选择字段或方法
+func (i *Interpreter) selectValue(sel *ast.SelectorExpr, container any) (any, error) {
+	if container == nil {
+		fmt.Printf("warn: 选择器表达式对象为undefined: %v.%s \n", sel.X, sel.Sel.Name)
+		return nil, nil
+	}
+
 	// 获取选择器名称
 	fieldName := sel.Sel.Name
 
@@ -1471,11 +2240,28 @@ func (i *Interpreter) evalSelectorExpr(sel *ast.SelectorExpr) (any, error) {
 			return val, nil
 		}
 	default:
+		// 宿主类型指针接收器的方法值（如结构体字段 mu sync.Mutex 的 Lock），绑定到原来的值的地址上，而不是副本上
+		if hostPointerMethod(reflect.TypeOf(container), fieldName) {
+			if addr, err := i.addressOf(sel.X); err == nil && addr.Type() == reflect.TypeOf(container) {
+				container = addr.Addr().Interface()
+			}
+		}
+
 		// 处理结构体和指针类型
-		if item, _ := globalReflectCache.get(container, fieldName); item != nil {
+		if item, typ := globalReflectCache.get(container, fieldName); typ != nil {
 			return item, nil
 		}
 
+		// 脚本类型上声明的方法，指针接收器的方法值绑定到变量的地址上
+		if method, copied, writeBack := i.methodValue(container, fieldName); method != nil {
+			if writeBack {
+				if addr, err := i.addressOf(sel.X); err == nil && addr.Type() == copied.Type() {
+					method, _, _ = i.methodValue(addr.Addr().Interface(), fieldName)
+				}
+			}
+			return method, nil
+		}
+
 		// 如果是map类型
 		if reflect.TypeOf(container).Kind() == reflect.Map {
 			// 使用map返回
@@ -1510,33 +2296,46 @@ func (i *Interpreter) evalDeclStmt(stmt *ast.DeclStmt) (any, error) {
 						varType = resolvedType
 					}
 
-					// 处理初始值
-					var value any = nil
+					// 处理初始值，支持 var a, b = f() 形式的多返回值
+					var values []any
 					if len(valueSpec.Values) > 0 {
 						var err error
-						value, err = i.eval(valueSpec.Values[0])
+						values, err = i.evalValues(valueSpec.Values, len(valueSpec.Names))
 						if err != nil {
 							return nil, err
 						}
-					} else if varType != nil {
-						// 如果有类型但没有初始值，创建零值
-						// 对于结构体类型，创建指针
-						if varType.Kind() == reflect.Struct {
-							// 创建指向结构体的指针
-							value = reflect.New(varType).Interface()
-						} else {
-							// 其他类型使用零值
-							value = reflect.New(varType).Elem().Interface()
-						}
 					}
 
 					// 为每个变量名赋值
-					for _, name := range valueSpec.Names {
-						i.scope.Store(name.Name, value)
+					for idx, name := range valueSpec.Names {
+						var value any = nil
+						if values != nil {
+							value = values[idx]
+						}
+						if varType == nil {
+							i.scope.Store(name.Name, value)
+							continue
+						}
+						// 声明了类型的变量保存在 varBox 中，初始值和之后赋的值都转换为声明的类型，
+						// 如 var b byte 之后 b = 200 得到的是 byte；var sb strings.Builder 的值是结构体本身，&sb 是 *strings.Builder
+						box := &varBox{ptr: reflect.New(varType)}
+						if err := box.set(value); err != nil {
+							return nil, fmt.Errorf("变量 %s: %v", name.Name, err)
+						}
+						i.scope.Store(name.Name, box)
 					}
 				}
 			}
 			return nil, nil
+		case token.TYPE:
+			for _, spec := range decl.Specs {
+				if err := i.evalTypeSpec(spec.(*ast.TypeSpec)); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		case token.CONST:
+			return nil, i.evalConstDecl(decl)
 		}
 	}
 	return nil, fmt.Errorf("不支持的声明类型: %T", stmt.Decl)
@@ -1547,16 +2346,19 @@ func (i *Interpreter) resolveType(expr ast.Expr) (reflect.Type, error) {
 	switch t := expr.(type) {
 	case *ast.Ident:
 		// 简单标识符，如 int, string 等
+		if typ, ok := basicTypes[t.Name]; ok {
+			return typ, nil
+		}
 		switch t.Name {
-		case "int":
-			return reflect.TypeOf(0), nil
-		case "string":
-			return reflect.TypeOf(""), nil
-		case "bool":
-			return reflect.TypeOf(false), nil
-		case "float64":
-			return reflect.TypeOf(0.0), nil
+		case "any":
+			return anyType, nil
+		case "error":
+			return errorType, nil
 		default:
+			// 脚本中定义的类型
+			if typ, ok := i.Get(t.Name).(reflect.Type); ok {
+				return typ, nil
+			}
 			return nil, fmt.Errorf("未知类型: %s", t.Name)
 		}
 	case *ast.SelectorExpr:
@@ -1617,132 +2419,201 @@ func (i *Interpreter) resolveType(expr ast.Expr) (reflect.Type, error) {
 			return nil, err
 		}
 		return reflect.MapOf(keyType, valueType), nil
+	case *ast.StarExpr:
+		// 指针类型
+		elemType, err := i.resolveType(t.X)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PtrTo(elemType), nil
+	case *ast.StructType:
+		// 匿名结构体
+		return i.structOf("", t)
+	case *ast.InterfaceType:
+		return anyType, nil
+	case *ast.ChanType:
+		elemType, err := i.resolveType(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		dir := reflect.BothDir
+		switch t.Dir {
+		case ast.SEND:
+			dir = reflect.SendDir
+		case ast.RECV:
+			dir = reflect.RecvDir
+		}
+		return reflect.ChanOf(dir, elemType), nil
+	case *ast.FuncType:
+		// 函数类型，如 var f func(int) int，零值为 nil，可以赋值为脚本函数
+		in, variadic, err := i.fieldTypes(t.Params)
+		if err != nil {
+			return nil, err
+		}
+		out, _, err := i.fieldTypes(t.Results)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.FuncOf(in, out, variadic), nil
+	case *ast.ParenExpr:
+		return i.resolveType(t.X)
 	default:
 		return nil, fmt.Errorf("不支持的类型表达式: %T", expr)
 	}
 }
 
+// 解析参数或结果列表中每一项的类型，一个字段有多个名字时重复对应的次数
+// 最后一项是 ...T 时返回 []T，并报告函数是可变参数的
+func (i *Interpreter) fieldTypes(fields *ast.FieldList) ([]reflect.Type, bool, error) {
+	if fields == nil {
+		return nil, false, nil
+	}
+	var types []reflect.Type
+	variadic := false
+	for _, field := range fields.List {
+		typeExpr := field.Type
+		if ellipsis, ok := typeExpr.(*ast.Ellipsis); ok {
+			typeExpr, variadic = ellipsis.Elt, true
+		}
+		typ, err := i.resolveType(typeExpr)
+		if err != nil {
+			return nil, false, err
+		}
+		if variadic {
+			typ = reflect.SliceOf(typ)
+		}
+		types = append(types, typ)
+		for n := 1; n < len(field.Names); n++ {
+			types = append(types, typ)
+		}
+	}
+	return types, variadic, nil
+}
+
 // 处理分支语句的方法
 func (i *Interpreter) evalBranchStmt(stmt *ast.BranchStmt) (any, error) {
+	var label string
+	if stmt.Label != nil {
+		label = stmt.Label.Name
+	}
 	switch stmt.Tok {
 	case token.BREAK:
-		return breakSentinel{}, nil
+		return breakSentinel{label}, nil
 	case token.CONTINUE:
-		return continueSentinel{}, nil
+		return continueSentinel{label}, nil
+	case token.GOTO:
+		return gotoSentinel{label}, nil
+	case token.FALLTHROUGH:
+		return fallthroughSentinel{}, nil
 	default:
 		return nil, fmt.Errorf("不支持的分支语句类型: %v", stmt.Tok)
 	}
 }
 
-// 定义哨兵类型用于处理 break 和 continue
-type breakSentinel struct{}
-type continueSentinel struct{}
-
-func (i *Interpreter) evalRangeStmt(node *ast.RangeStmt) (any, error) {
-	// 获取要遍历的值
-	val, err := i.eval(node.X)
-	if err != nil {
-		return nil, err
+// 定义哨兵类型用于处理 break、continue、goto 和 fallthrough，label 为空表示没有标签
+type breakSentinel struct{ label string }
+type continueSentinel struct{ label string }
+type gotoSentinel struct{ label string }
+type fallthroughSentinel struct{}
+
+func (breakSentinel) controlFlow()       {}
+func (continueSentinel) controlFlow()    {}
+func (gotoSentinel) controlFlow()        {}
+func (fallthroughSentinel) controlFlow() {}
+
+// 处理带标签的语句，标签作用于其后的循环或 switch；其它语句只能作为 goto 的目标
+func (i *Interpreter) evalLabeledStmt(stmt *ast.LabeledStmt) (any, error) {
+	label := stmt.Label.Name
+	switch s := stmt.Stmt.(type) {
+	case *ast.ForStmt:
+		return i.evalForStmt(s, label)
+	case *ast.RangeStmt:
+		return i.evalRangeStmt(s, label)
+	case *ast.SwitchStmt:
+		return i.evalSwitchStmt(s, label)
+	case *ast.TypeSwitchStmt:
+		return i.evalTypeSwitchStmt(s, label)
+	case *ast.SelectStmt:
+		return i.evalSelectStmt(s, label)
 	}
+	return i.eval(stmt.Stmt)
+}
 
-	// 使用反射获取值
-	rval := reflect.ValueOf(val)
-
-	switch rval.Kind() {
-	case reflect.Slice, reflect.Array:
-		// 遍历切片或数组
-		for n := 0; n < rval.Len(); n++ {
-			if node.Key != nil {
-				// 设置索引变量
-				i.scope.Store(node.Key.(*ast.Ident).Name, n)
-			}
-			if node.Value != nil {
-				// 设置值变量
-				i.scope.Store(node.Value.(*ast.Ident).Name, rval.Index(n).Interface())
-			}
-			// 执行循环体
-			result, err := i.eval(node.Body)
-			if err != nil {
-				return nil, err
-			}
-			// 处理 break 和 continue
-			if _, ok := result.(breakSentinel); ok {
-				return nil, nil
-			}
-			if _, ok := result.(continueSentinel); ok {
-				continue
-			}
+// 语句列表中 goto 的目标语句的位置
+func labelIndex(list []ast.Stmt, label string) int {
+	for idx, stmt := range list {
+		if labeled, ok := stmt.(*ast.LabeledStmt); ok && labeled.Label.Name == label {
+			return idx
 		}
+	}
+	return -1
+}
 
-	case reflect.Map:
-		// 遍历 map
-		iter := rval.MapRange()
-		for iter.Next() {
-			if node.Key != nil {
-				// 设置键变量
-				i.scope.Store(node.Key.(*ast.Ident).Name, iter.Key().Interface())
-			}
-			if node.Value != nil {
-				// 设置值变量
-				i.scope.Store(node.Value.(*ast.Ident).Name, iter.Value().Interface())
-			}
-			// 执行循环体
-			result, err := i.eval(node.Body)
-			if err != nil {
-				return nil, err
-			}
-			// 处理 break 和 continue
-			if _, ok := result.(breakSentinel); ok {
-				return nil, nil
-			}
-			if _, ok := result.(continueSentinel); ok {
-				continue
-			}
+// 处理循环体产生的控制流信号，exit 为 true 时退出循环，并将 signal（如 return、外层循环的 break）继续向外传递
+func loopControl(result any, label string) (exit bool, signal any) {
+	switch r := result.(type) {
+	case breakSentinel:
+		if r.label == "" || r.label == label {
+			return true, nil
 		}
-
-	default:
-		return nil, fmt.Errorf("range: cannot range over %v (type %T)", val, val)
+		return true, result
+	case continueSentinel:
+		if r.label == "" || r.label == label {
+			return false, nil
+		}
+		return true, result
+	case controlFlow:
+		return true, result
 	}
+	return false, nil
+}
 
-	return nil, nil
+// switch 中的 break 只退出 switch 本身
+func switchControl(result any, err error, label string) (any, error) {
+	if r, ok := result.(breakSentinel); ok && (r.label == "" || r.label == label) {
+		return nil, err
+	}
+	if _, ok := result.(fallthroughSentinel); ok {
+		return nil, fmt.Errorf("fallthrough 只能用于 switch 的 case 的最后一条语句")
+	}
+	return result, err
 }
 
 // 处理一元表达式
 func (i *Interpreter) evalUnaryExpr(expr *ast.UnaryExpr) (any, error) {
+	// 取地址时操作数不求值
+	if expr.Op == token.AND {
+		return i.evalAddressOf(expr.X)
+	}
+	if expr.Op == token.ARROW {
+		value, _, err := i.evalRecv(expr)
+		return value, err
+	}
+
 	// 计算操作数
 	operand, err := i.eval(expr.X)
 	if err != nil {
 		return nil, err
 	}
+	return unaryOp(expr.Op, operand)
+}
 
-	switch expr.Op {
+// 对已求值的操作数进行一元运算，不包括取地址和接收
+func unaryOp(op token.Token, operand any) (any, error) {
+	switch op {
 	case token.NOT: // !
 		return !toBool(operand), nil
-	case token.SUB: // -
-		switch v := operand.(type) {
-		case int:
-			return -v, nil
-		case float64:
-			return -v, nil
-		default:
-			return nil, fmt.Errorf("一元减号操作不支持类型: %T", operand)
-		}
-	case token.ADD: // +
-		switch v := operand.(type) {
-		case int:
-			return v, nil
-		case float64:
-			return v, nil
-		default:
-			return nil, fmt.Errorf("一元加号操作不支持类型: %T", operand)
-		}
+	case token.SUB, token.ADD, token.XOR: // - + ^
+		return unaryArith(op, operand)
 	default:
-		return nil, fmt.Errorf("不支持的一元操作符: %v", expr.Op)
+		return nil, fmt.Errorf("不支持的一元操作符: %v", op)
 	}
 }
 
 // 处理 switch 语句
-func (i *Interpreter) evalSwitchStmt(stmt *ast.SwitchStmt) (any, error) {
+func (i *Interpreter) evalSwitchStmt(stmt *ast.SwitchStmt, label string) (any, error) {
+	i = i.withScope(&Scope{parent: i.scope})
+
 	// 如果有初始化语句，先执行
 	if stmt.Init != nil {
 		_, err := i.eval(stmt.Init)
@@ -1751,8 +2622,8 @@ func (i *Interpreter) evalSwitchStmt(stmt *ast.SwitchStmt) (any, error) {
 		}
 	}
 
-	// 计算 switch 表达式的值
-	var tag any
+	// 计算 switch 表达式的值，没有表达式时相当于 switch true
+	var tag any = true
 	var err error
 	if stmt.Tag != nil {
 		tag, err = i.eval(stmt.Tag)
@@ -1761,15 +2632,11 @@ func (i *Interpreter) evalSwitchStmt(stmt *ast.SwitchStmt) (any, error) {
 		}
 	}
 
-	// 遍历所有的 case
-	for _, caseClause := range stmt.Body.List {
+	// 按顺序匹配 case，default 在都不匹配时执行
+	matched := -1
+	for idx, caseClause := range stmt.Body.List {
 		clause := caseClause.(*ast.CaseClause)
-
-		// default 子句
 		if clause.List == nil {
-			if len(stmt.Body.List) > 0 {
-				return i.evalBlockStmt(&ast.BlockStmt{List: clause.Body})
-			}
 			continue
 		}
 
@@ -1781,18 +2648,41 @@ func (i *Interpreter) evalSwitchStmt(stmt *ast.SwitchStmt) (any, error) {
 			}
 
 			// 比较 case 值和 switch 表达式的值
-			equal, err := equal(tag, caseVal)
+			equal, err := equal(tag, caseVal, i.untypedOperands(tag, caseVal, stmt.Tag, expr))
 			if err != nil {
 				return nil, err
 			}
-
-			// 如果匹配，执行对应的语句块
 			if equal {
-				return i.evalBlockStmt(&ast.BlockStmt{List: clause.Body})
+				matched = idx
+				break
+			}
+		}
+		if matched >= 0 {
+			break
+		}
+	}
+	if matched < 0 {
+		for idx, caseClause := range stmt.Body.List {
+			if caseClause.(*ast.CaseClause).List == nil {
+				matched = idx
 			}
 		}
 	}
+	if matched < 0 {
+		return nil, nil
+	}
 
+	// 执行匹配的 case，fallthrough 时继续执行下一个 case
+	for idx := matched; idx < len(stmt.Body.List); idx++ {
+		result, err := i.evalBlockStmt(&ast.BlockStmt{List: stmt.Body.List[idx].(*ast.CaseClause).Body})
+		if _, ok := result.(fallthroughSentinel); ok && err == nil {
+			if idx == len(stmt.Body.List)-1 {
+				return nil, fmt.Errorf("最后一个 case 中不能使用 fallthrough")
+			}
+			continue
+		}
+		return switchControl(result, err, label)
+	}
 	return nil, nil
 }
 
@@ -0,0 +1,98 @@
+package goscript
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFuncDecl(t *testing.T) {
+	// 函数声明在使用之后，且可以互相递归
+	t.Run("Hoisting And Mutual Recursion", func(t *testing.T) {
+		interp := NewInterpreter()
+		result, err := interp.Interpret(`
+		r := isEven(10)
+
+		func isEven(n int) bool {
+			if n == 0 {
+				return true
+			} else {
+				return isOdd(n - 1)
+			}
+		}
+
+		func isOdd(n int) bool {
+			if n == 0 {
+				return false
+			} else {
+				return isEven(n - 1)
+			}
+		}
+		return r
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != true {
+			t.Errorf("Expected true, got %v", result)
+		}
+	})
+
+	// 参数和返回值中的 interface{} 不影响声明的识别
+	t.Run("Interface Params", func(t *testing.T) {
+		interp := NewInterpreter()
+		result, err := interp.Interpret(`
+		func pair(a interface{}, b interface{}) (interface{}, interface{}) {
+			return b, a
+		}
+		x, y := pair(1, "a")
+		return y, x
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if r, ok := result.([]any); !ok || r[0] != 1 || r[1] != "a" {
+			t.Errorf("Expected [1 a], got %v", result)
+		}
+	})
+
+	// 执行结束后宿主可以调用脚本中定义的函数
+	t.Run("Call From Go", func(t *testing.T) {
+		interp := NewInterpreter()
+		_, err := interp.Interpret(`
+		func greet(name string) string {
+			return "hello " + name
+		}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		result, err := interp.Call("greet", "go")
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != "hello go" {
+			t.Errorf("Expected hello go, got %v", result)
+		}
+		if _, err := interp.Call("missing"); err == nil {
+			t.Errorf("Expected error for undefined function")
+		}
+	})
+
+	// 移出函数声明后脚本中的行号保持不变
+	t.Run("Line Numbers", func(t *testing.T) {
+		interp := NewInterpreter()
+		_, err := interp.Interpret(`
+		func f() {
+			print("f")
+		}
+		panic("here")
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Fatalf("Expected *PanicError, got %v", err)
+		}
+		if p.Pos.Line != 5 {
+			t.Errorf("Expected line 5, got %v", p.Pos)
+		}
+	})
+}
@@ -0,0 +1,414 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+// genericFunc 脚本中声明的泛型函数，调用时根据实参推断类型参数，或者通过 f[int] 显式实例化
+type genericFunc struct {
+	// 声明函数的解释器，实例化的函数在其作用域中执行
+	interp *Interpreter
+	name   string
+	params []*ast.Ident
+	// 类型参数的约束，与 params 一一对应
+	constraints []ast.Expr
+	fnType      *ast.FuncType
+	body        *ast.BlockStmt
+	// 已经显式指定的类型参数
+	bound map[string]reflect.Type
+}
+
+// typeConstraint 脚本中声明的只能用作约束的接口类型，如 type Number interface { ~int | ~float64 }
+type typeConstraint struct {
+	name string
+	expr *ast.InterfaceType
+}
+
+// 创建泛型函数
+func (i *Interpreter) newGeneric(decl *ast.FuncDecl) *genericFunc {
+	g := &genericFunc{interp: i, name: decl.Name.Name, fnType: decl.Type, body: decl.Body}
+	for _, field := range decl.Type.TypeParams.List {
+		for _, name := range field.Names {
+			g.params = append(g.params, name)
+			g.constraints = append(g.constraints, field.Type)
+		}
+	}
+	return g
+}
+
+// 接口中是否包含类型集合（如 ~int | float64），这样的接口只能用作约束
+func isConstraint(it *ast.InterfaceType) bool {
+	for _, field := range it.Methods.List {
+		if len(field.Names) == 0 {
+			if _, ok := field.Type.(*ast.Ident); !ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 显式实例化 f[int] 或 f[int, string]，指定了全部类型参数时返回普通的函数
+func (i *Interpreter) instantiate(g *genericFunc, typeExprs []ast.Expr) (any, error) {
+	if len(typeExprs) > len(g.params) {
+		return nil, fmt.Errorf("%s 的类型参数过多: 需要 %d 个, 得到 %d 个", g.name, len(g.params), len(typeExprs))
+	}
+	bound := make(map[string]reflect.Type, len(g.params))
+	for idx, expr := range typeExprs {
+		typ, err := i.resolveType(expr)
+		if err != nil {
+			return nil, err
+		}
+		bound[g.params[idx].Name] = typ
+	}
+	partial := *g
+	partial.bound = bound
+	if len(bound) < len(g.params) {
+		// 其余的类型参数在调用时推断
+		return &partial, nil
+	}
+	return partial.instance(bound)
+}
+
+// 调用泛型函数，未指定的类型参数根据实参推断
+// 实参是函数字面量时（如 Map(xs, func(n int) string {...})），根据字面量声明的类型推断
+func (i *Interpreter) callGeneric(call *ast.CallExpr, g *genericFunc, args []any) (any, error) {
+	bound := make(map[string]reflect.Type, len(g.params))
+	for name, typ := range g.bound {
+		bound[name] = typ
+	}
+	if call != nil && !call.Ellipsis.IsValid() && len(call.Args) == len(args) {
+		for idx, param := range paramExprs(g.fnType) {
+			if idx >= len(call.Args) {
+				break
+			}
+			lit, ok := call.Args[idx].(*ast.FuncLit)
+			fnType, isFunc := param.(*ast.FuncType)
+			if !ok || !isFunc {
+				continue
+			}
+			if err := i.unifyFuncLit(g, bound, fnType, lit.Type); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := g.infer(bound, args); err != nil {
+		return nil, err
+	}
+	fn, err := g.instance(bound)
+	if err != nil {
+		return nil, err
+	}
+	return fn(withCaller(i.frame, args)...)
+}
+
+// 根据函数字面量声明的参数和结果类型推断类型参数
+func (i *Interpreter) unifyFuncLit(g *genericFunc, bound map[string]reflect.Type, param, lit *ast.FuncType) error {
+	unify := func(params, lits []ast.Expr) error {
+		for idx := 0; idx < len(params) && idx < len(lits); idx++ {
+			typ, err := i.resolveType(lits[idx])
+			if err != nil {
+				return err
+			}
+			if err := g.unifyType(bound, params[idx], typ); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := unify(paramExprs(param), paramExprs(lit)); err != nil {
+		return err
+	}
+	return unify(fieldExprs(param.Results), fieldExprs(lit.Results))
+}
+
+// 函数的每个参数的类型表达式，func(a, b int) 展开为两个参数
+func paramExprs(fnType *ast.FuncType) []ast.Expr {
+	return fieldExprs(fnType.Params)
+}
+
+// 字段列表中每个字段的类型表达式
+func fieldExprs(list *ast.FieldList) []ast.Expr {
+	if list == nil {
+		return nil
+	}
+	var exprs []ast.Expr
+	for _, field := range list.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		for n := 0; n < count; n++ {
+			exprs = append(exprs, field.Type)
+		}
+	}
+	return exprs
+}
+
+// 使用给定的类型参数实例化函数，类型参数作为类型保存在函数的外层作用域中
+func (g *genericFunc) instance(bound map[string]reflect.Type) (func(...any) (any, error), error) {
+	typeScope := &Scope{parent: g.interp.scope}
+	for idx, param := range g.params {
+		typ := bound[param.Name]
+		if err := g.interp.withScope(typeScope).satisfies(typ, g.constraints[idx]); err != nil {
+			return nil, fmt.Errorf("%s 的类型参数 %s: %v", g.name, param.Name, err)
+		}
+		typeScope.Store(param.Name, typ)
+	}
+	fn := g.interp.withScope(typeScope).newFunction(g.fnType, g.body)
+
+	// 类型为类型参数的数值参数转换为实例化的类型，如 Max[float64](1, 2) 中的 1 和 2
+	var paramTypes []reflect.Type
+	for _, expr := range paramExprs(g.fnType) {
+		var typ reflect.Type
+		if ident, ok := expr.(*ast.Ident); ok {
+			typ = bound[ident.Name]
+		}
+		paramTypes = append(paramTypes, typ)
+	}
+	return func(args ...any) (any, error) {
+		caller, args := callerOf(args)
+		for idx, arg := range args {
+			if idx < len(paramTypes) && paramTypes[idx] != nil && isNumber(arg) && isNumberKind(paramTypes[idx].Kind()) {
+				converted, err := toValue(arg, paramTypes[idx])
+				if err != nil {
+					return nil, err
+				}
+				args[idx] = converted.Interface()
+			}
+		}
+		return fn(withCaller(caller, args)...)
+	}, nil
+}
+
+// 根据实参推断类型参数
+func (g *genericFunc) infer(bound map[string]reflect.Type, args []any) error {
+	exprs := paramExprs(g.fnType)
+	for idx, arg := range args {
+		if len(exprs) == 0 {
+			break
+		}
+		expr := exprs[len(exprs)-1]
+		if idx < len(exprs) {
+			expr = exprs[idx]
+		}
+		if ellipsis, ok := expr.(*ast.Ellipsis); ok {
+			// 可变参数的每个实参都用于推断元素类型
+			expr = ellipsis.Elt
+		} else if idx >= len(exprs) {
+			break
+		}
+		if err := g.unifyValue(bound, expr, arg); err != nil {
+			return err
+		}
+	}
+	for _, param := range g.params {
+		if bound[param.Name] == nil {
+			return fmt.Errorf("无法推断 %s 的类型参数 %s", g.name, param.Name)
+		}
+	}
+	return nil
+}
+
+// 类型参数的名称
+func (g *genericFunc) typeParam(expr ast.Expr) (string, bool) {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	for _, param := range g.params {
+		if param.Name == ident.Name {
+			return ident.Name, true
+		}
+	}
+	return "", false
+}
+
+// 根据实参的值推断类型，脚本中的 []any 和 map 根据其中的元素推断
+func (g *genericFunc) unifyValue(bound map[string]reflect.Type, expr ast.Expr, value any) error {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	switch t := expr.(type) {
+	case *ast.ArrayType:
+		if list, ok := value.([]any); ok {
+			for _, elem := range list {
+				if err := g.unifyValue(bound, t.Elt, elem); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case *ast.MapType:
+		if v.Kind() == reflect.Map && v.Type().Elem() == anyType {
+			iter := v.MapRange()
+			for iter.Next() {
+				if err := g.unifyValue(bound, t.Key, iter.Key().Interface()); err != nil {
+					return err
+				}
+				if err := g.unifyValue(bound, t.Value, iter.Value().Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return g.unifyType(bound, expr, v.Type())
+}
+
+// 根据实参的类型推断类型参数
+func (g *genericFunc) unifyType(bound map[string]reflect.Type, expr ast.Expr, typ reflect.Type) error {
+	if name, ok := g.typeParam(expr); ok {
+		prev := bound[name]
+		switch {
+		case prev == nil || prev == typ:
+			bound[name] = typ
+		case isNumberKind(prev.Kind()) && isNumberKind(typ.Kind()) && g.bound[name] == nil:
+			// 脚本中的数值字面量类似于无类型常量，如 Max(1, 2.5) 推断为 float64
+			// 推断时只有实参的值，int 和 float64 的实参都视为无类型常量
+			promoted, err := numericType(prev, typ, defaultUntyped(prev, typ))
+			if err != nil {
+				return fmt.Errorf("%s 的类型参数 %s 推断出不一致的类型: %s 和 %s", g.name, name, typeName(prev), typeName(typ))
+			}
+			bound[name] = promoted
+		case isNumberKind(prev.Kind()) && (typ == intType || typ == float64Type):
+			// 显式指定的数值类型参数，字面量会被转换
+		default:
+			return fmt.Errorf("%s 的类型参数 %s 推断出不一致的类型: %s 和 %s", g.name, name, typeName(prev), typeName(typ))
+		}
+		return nil
+	}
+	switch t := expr.(type) {
+	case *ast.ArrayType:
+		if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+			return g.unifyType(bound, t.Elt, typ.Elem())
+		}
+	case *ast.MapType:
+		if typ.Kind() == reflect.Map {
+			if err := g.unifyType(bound, t.Key, typ.Key()); err != nil {
+				return err
+			}
+			return g.unifyType(bound, t.Value, typ.Elem())
+		}
+	case *ast.StarExpr:
+		if typ.Kind() == reflect.Ptr {
+			return g.unifyType(bound, t.X, typ.Elem())
+		}
+	case *ast.ChanType:
+		if typ.Kind() == reflect.Chan {
+			return g.unifyType(bound, t.Value, typ.Elem())
+		}
+	}
+	// 其余情况（如脚本中的函数）无法用于推断
+	return nil
+}
+
+// 检查类型是否满足约束
+func (i *Interpreter) satisfies(typ reflect.Type, constraint ast.Expr) error {
+	ok, err := i.inTypeSet(typ, constraint)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s 不满足 %s", typeName(typ), constraintString(constraint))
+	}
+	return nil
+}
+
+// 类型是否属于约束的类型集合
+func (i *Interpreter) inTypeSet(typ reflect.Type, constraint ast.Expr) (bool, error) {
+	switch c := constraint.(type) {
+	case *ast.Ident:
+		switch c.Name {
+		case "any":
+			return true, nil
+		case "comparable":
+			return typ.Comparable(), nil
+		}
+		if tc, ok := i.Get(c.Name).(*typeConstraint); ok {
+			return i.inTypeSet(typ, tc.expr)
+		}
+	case *ast.ParenExpr:
+		return i.inTypeSet(typ, c.X)
+	case *ast.BinaryExpr:
+		// 联合类型 A | B
+		if c.Op == token.OR {
+			ok, err := i.inTypeSet(typ, c.X)
+			if ok || err != nil {
+				return ok, err
+			}
+			return i.inTypeSet(typ, c.Y)
+		}
+	case *ast.UnaryExpr:
+		// ~T 包括底层类型为 T 的类型
+		if c.Op == token.TILDE {
+			target, err := i.resolveType(c.X)
+			if err != nil {
+				return false, err
+			}
+			if target.PkgPath() == "" && target.Name() == target.Kind().String() {
+				// 预声明的类型，如 ~int、~string
+				return typ.Kind() == target.Kind(), nil
+			}
+			return typ == target || typ.Kind() == target.Kind() && typ.ConvertibleTo(target), nil
+		}
+	case *ast.InterfaceType:
+		// 接口中的每个元素都需要满足
+		for _, field := range c.Methods.List {
+			if len(field.Names) > 0 {
+				// 方法约束只检查宿主类型的方法
+				for _, name := range field.Names {
+					if _, ok := typ.MethodByName(name.Name); !ok {
+						if _, ok := reflect.PtrTo(typ).MethodByName(name.Name); !ok && i.lookupMethod(typ, name.Name) == nil {
+							return false, nil
+						}
+					}
+				}
+				continue
+			}
+			ok, err := i.inTypeSet(typ, field.Type)
+			if !ok || err != nil {
+				return ok, err
+			}
+		}
+		return true, nil
+	}
+	target, err := i.resolveType(constraint)
+	if err != nil {
+		return false, err
+	}
+	if target.Kind() == reflect.Interface {
+		return typ.Implements(target), nil
+	}
+	return typ == target, nil
+}
+
+// 约束在错误信息中的写法
+func constraintString(expr ast.Expr) string {
+	switch c := expr.(type) {
+	case *ast.Ident:
+		return c.Name
+	case *ast.BinaryExpr:
+		return constraintString(c.X) + " | " + constraintString(c.Y)
+	case *ast.UnaryExpr:
+		return "~" + constraintString(c.X)
+	case *ast.SelectorExpr:
+		return constraintString(c.X) + "." + c.Sel.Name
+	case *ast.ArrayType:
+		return "[]" + constraintString(c.Elt)
+	case *ast.InterfaceType:
+		var elems []string
+		for _, field := range c.Methods.List {
+			if len(field.Names) == 0 {
+				elems = append(elems, constraintString(field.Type))
+			}
+		}
+		return "interface{ " + strings.Join(elems, "; ") + " }"
+	}
+	return fmt.Sprintf("%T", expr)
+}
@@ -0,0 +1,137 @@
+package goscript
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerics(t *testing.T) {
+	bindings := map[string]any{
+		"ints":      []int{3, 1, 2},
+		"floats":    []float64{1.5, 2.5},
+		"durations": []time.Duration{time.Second, time.Minute},
+		"names":     []string{"b", "a", "b"},
+	}
+	// 联合类型约束，类型参数根据宿主切片和脚本中的值推断
+	t.Run("Union Constraints", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		type Number interface {
+			~int | ~int64 | ~float64
+		}
+
+		func Sum[T Number](xs []T) T {
+			var total T
+			for _, x := range xs {
+				total += x
+			}
+			return total
+		}
+
+		func Max[T int | float64 | string](a, b T) T {
+			if a > b {
+				return a
+			}
+			return b
+		}
+
+		[]any{Sum(ints), Sum(floats), Sum(durations), Sum([]any{1, 2, 3}), Max(1, 2.5), Max("a", "b")}
+		`)
+		expected := []any{6, 4.0, time.Second + time.Minute, 6, 2.5, "b"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// comparable 约束和多个类型参数
+	t.Run("Comparable", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		func Uniq[T comparable](xs []T) []T {
+			seen := map[any]any{}
+			out := []T{}
+			for _, x := range xs {
+				if !seen[x] {
+					seen[x] = true
+					out = append(out, x)
+				}
+			}
+			return out
+		}
+
+		func Map[T any, R any](xs []T, f func(T) R) []R {
+			out := []R{}
+			for _, x := range xs {
+				out = append(out, f(x))
+			}
+			return out
+		}
+
+		[]any{Uniq(names), Map(ints, func(n int) string { return "n" + n })}
+		`)
+		expected := []any{[]any{"b", "a"}, []any{"n3", "n1", "n2"}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 显式实例化，类型参数可以用于零值和 make
+	t.Run("Explicit Instantiation", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		func Zero[T any]() T {
+			var zero T
+			return zero
+		}
+
+		func Pair[K comparable, V any](k K, v V) any {
+			return []any{k, v}
+		}
+
+		func Max[T int | float64](a, b T) T {
+			if a > b {
+				return a
+			}
+			return b
+		}
+
+		type Point struct {
+			X int
+		}
+
+		half := Pair[string]
+		[]any{Zero[int](), Zero[string](), Zero[Point]().X, Pair[string, int]("a", 1), half("b", 2), Max[float64](1, 2)}
+		`)
+		expected := []any{0, "", 0, []any{"a", 1}, []any{"b", 2}, 2.0}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 不满足约束和无法推断类型参数时报错
+	t.Run("Errors", func(t *testing.T) {
+		cases := map[string]string{
+			"不满足": `
+			func Sum[T int | float64](xs []T) T {
+				return xs[0]
+			}
+			Sum(names)`,
+			"无法推断": `
+			func Zero[T any]() T {
+				var zero T
+				return zero
+			}
+			Zero()`,
+			"不一致": `
+			func Same[T any](a, b T) bool {
+				return true
+			}
+			Same("a", 1)`,
+		}
+		for want, code := range cases {
+			_, err := newTestInterpreter(bindings).Interpret(code)
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error containing %s, got %v", want, err)
+			}
+		}
+	})
+}
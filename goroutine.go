@@ -0,0 +1,284 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"reflect"
+)
+
+// withScope 返回在给定作用域中执行的解释器副本
+// 代码块和函数调用都在各自的副本上执行而不修改共享的解释器，因此多个goroutine可以并发执行脚本
+func (i *Interpreter) withScope(scope *Scope) *Interpreter {
+	c := *i
+	c.scope = scope
+	return &c
+}
+
+// callerArg 解释器调用脚本函数时作为隐藏的第一个参数传入调用者的帧，
+// 脚本函数在定义时的作用域中执行，但recover需要沿着调用链查找panic
+// 宿主直接调用脚本函数时没有这个参数
+type callerArg struct {
+	frame *callFrame
+}
+
+// 在参数前加上调用者的帧
+func withCaller(frame *callFrame, args []any) []any {
+	return append([]any{callerArg{frame}}, args...)
+}
+
+// 取出调用者的帧和实际的参数
+func callerOf(args []any) (*callFrame, []any) {
+	if len(args) > 0 {
+		if c, ok := args[0].(callerArg); ok {
+			return c.frame, args[1:]
+		}
+	}
+	return nil, args
+}
+
+// OnGoroutineError 设置脚本启动的goroutine中未处理的错误和panic的处理函数，Fork 出的解释器继承该设置
+// 这些错误无法作为脚本的执行结果返回给宿主，没有设置时输出到标准错误，panic 以 *PanicError 的形式传入
+// 处理函数在出错的goroutine中调用，可能被并发调用
+func (i *Interpreter) OnGoroutineError(fn func(err error)) {
+	i.goroutineError = fn
+}
+
+// 处理goroutine中未处理的错误
+func (i *Interpreter) reportGoroutineError(err error) {
+	if i.goroutineError != nil {
+		i.goroutineError(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "goroutine 执行出错: %v\n", err)
+}
+
+// 处理 go 语句，函数和参数在当前goroutine中求值，调用在新的goroutine中执行
+// 新的goroutine中未处理的错误和panic无法返回给宿主，交给 OnGoroutineError 设置的处理函数
+func (i *Interpreter) evalGoStmt(stmt *ast.GoStmt) (any, error) {
+	fn, err := i.evalCallee(stmt.Call.Fun)
+	if err != nil {
+		return nil, err
+	}
+	args, err := i.evalCallArgs(stmt.Call)
+	if err != nil {
+		return nil, err
+	}
+	// 新的goroutine没有调用者
+	routine := i.withScope(i.scope)
+	routine.frame = nil
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				routine.reportGoroutineError(&PanicError{Value: r})
+			}
+		}()
+		if _, err := routine.callFunction(stmt.Call, fn, args); err != nil {
+			routine.reportGoroutineError(err)
+		}
+	}()
+	return nil, nil
+}
+
+// 处理 ch <- v
+func (i *Interpreter) evalSendStmt(stmt *ast.SendStmt) (any, error) {
+	ch, err := i.evalChan(stmt.Chan)
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.eval(stmt.Value)
+	if err != nil {
+		return nil, err
+	}
+	v, err := chanValue(ch, value)
+	if err != nil {
+		return nil, err
+	}
+	return nil, i.guardChan(stmt.Pos(), func() { ch.Send(v) })
+}
+
+// 计算channel表达式，nil channel 与go一样永远阻塞，这里作为错误处理
+func (i *Interpreter) evalChan(expr ast.Expr) (reflect.Value, error) {
+	value, err := i.eval(expr)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return toChan(value)
+}
+
+// 检查已求值的值是非nil的channel
+func toChan(value any) (reflect.Value, error) {
+	ch := reflect.ValueOf(value)
+	if value != nil && ch.Kind() != reflect.Chan {
+		return reflect.Value{}, fmt.Errorf("%s 不是channel", typeName(ch.Type()))
+	}
+	if value == nil || ch.IsNil() {
+		return reflect.Value{}, fmt.Errorf("不能在nil channel上发送或接收")
+	}
+	return ch, nil
+}
+
+// 将发送的值转换为channel的元素类型
+func chanValue(ch reflect.Value, value any) (reflect.Value, error) {
+	v, err := toValue(value, ch.Type().Elem())
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("不能将 %s 发送到 %s: %v", typeName(reflect.TypeOf(value)), typeName(ch.Type()), err)
+	}
+	return v, nil
+}
+
+// 执行channel操作，向已关闭的channel发送等操作产生的panic转换为 PanicError
+func (i *Interpreter) guardChan(pos token.Pos, op func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: fmt.Sprint(r), Pos: i.position(pos)}
+		}
+	}()
+	op()
+	return nil
+}
+
+// 处理 <-ch，ok 为 false 表示channel已关闭，此时返回元素类型的零值
+func (i *Interpreter) evalRecv(expr *ast.UnaryExpr) (value any, ok bool, err error) {
+	value, err = i.eval(expr.X)
+	if err != nil {
+		return nil, false, err
+	}
+	return i.recv(expr, value)
+}
+
+// 从已求值的channel中接收，expr 用于确定panic的位置
+func (i *Interpreter) recv(expr *ast.UnaryExpr, value any) (any, bool, error) {
+	ch, err := toChan(value)
+	if err != nil {
+		return nil, false, err
+	}
+	var v reflect.Value
+	var ok bool
+	err = i.guardChan(expr.Pos(), func() { v, ok = ch.Recv() })
+	if err != nil {
+		return nil, false, err
+	}
+	return v.Interface(), ok, nil
+}
+
+// 处理 v, ok := <-ch
+func (i *Interpreter) evalRecvOk(expr *ast.UnaryExpr) ([]any, error) {
+	value, ok, err := i.evalRecv(expr)
+	if err != nil {
+		return nil, err
+	}
+	return []any{value, ok}, nil
+}
+
+// 关闭channel，关闭已关闭的channel会panic
+func (i *Interpreter) closeChan(call *ast.CallExpr, value any) error {
+	ch := reflect.ValueOf(value)
+	if ch.Kind() != reflect.Chan {
+		return fmt.Errorf("close 的参数必须是channel，得到: %s", typeName(reflect.TypeOf(value)))
+	}
+	return i.guardChan(call.Pos(), ch.Close)
+}
+
+// 处理 select 语句，多个case同时就绪时随机选择一个，有default时不阻塞
+func (i *Interpreter) evalSelectStmt(stmt *ast.SelectStmt, label string) (any, error) {
+	clauses := make([]*ast.CommClause, 0, len(stmt.Body.List))
+	cases := make([]reflect.SelectCase, 0, len(stmt.Body.List))
+	for _, s := range stmt.Body.List {
+		clause := s.(*ast.CommClause)
+		selectCase, err := i.selectCase(clause.Comm)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+		cases = append(cases, selectCase)
+	}
+
+	var chosen int
+	var recv reflect.Value
+	var ok bool
+	err := i.guardChan(stmt.Pos(), func() { chosen, recv, ok = reflect.Select(cases) })
+	if err != nil {
+		return nil, err
+	}
+
+	// 每个 case 有自己的作用域，接收的值赋给 case 中的变量
+	clause := clauses[chosen]
+	i = i.withScope(&Scope{parent: i.scope})
+	if assign, isAssign := clause.Comm.(*ast.AssignStmt); isAssign {
+		values := []any{recv.Interface(), ok}
+		for idx, lhs := range assign.Lhs {
+			if err := i.assign(lhs, values[idx], assign.Tok == token.DEFINE); err != nil {
+				return nil, err
+			}
+		}
+	}
+	result, err := i.evalStmtList(clause.Body)
+	if _, ok := result.(fallthroughSentinel); ok {
+		return nil, fmt.Errorf("select 中不能使用 fallthrough")
+	}
+	return switchControl(result, err, label)
+}
+
+// 将 select 的 case 转换为 reflect.SelectCase，nil channel 的 case 永远不会被选中
+func (i *Interpreter) selectCase(comm ast.Stmt) (reflect.SelectCase, error) {
+	var recvExpr ast.Expr
+	switch s := comm.(type) {
+	case nil:
+		return reflect.SelectCase{Dir: reflect.SelectDefault}, nil
+	case *ast.SendStmt:
+		ch, err := i.evalSelectChan(s.Chan)
+		if err != nil || !ch.IsValid() {
+			return reflect.SelectCase{Dir: reflect.SelectSend}, err
+		}
+		sent, err := i.eval(s.Value)
+		if err != nil {
+			return reflect.SelectCase{}, err
+		}
+		v, err := chanValue(ch, sent)
+		if err != nil {
+			return reflect.SelectCase{}, err
+		}
+		return reflect.SelectCase{Dir: reflect.SelectSend, Chan: ch, Send: v}, nil
+	case *ast.ExprStmt:
+		recvExpr = s.X
+	case *ast.AssignStmt:
+		if len(s.Rhs) != 1 || len(s.Lhs) > 2 {
+			return reflect.SelectCase{}, fmt.Errorf("select 的 case 中只能有一个接收操作")
+		}
+		recvExpr = s.Rhs[0]
+	default:
+		return reflect.SelectCase{}, fmt.Errorf("select 的 case 必须是发送或接收操作")
+	}
+
+	for {
+		paren, ok := recvExpr.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		recvExpr = paren.X
+	}
+	unary, ok := recvExpr.(*ast.UnaryExpr)
+	if !ok || unary.Op != token.ARROW {
+		return reflect.SelectCase{}, fmt.Errorf("select 的 case 必须是发送或接收操作")
+	}
+	ch, err := i.evalSelectChan(unary.X)
+	return reflect.SelectCase{Dir: reflect.SelectRecv, Chan: ch}, err
+}
+
+// 计算 select 中的channel表达式，nil channel 返回无效的值
+func (i *Interpreter) evalSelectChan(expr ast.Expr) (reflect.Value, error) {
+	value, err := i.eval(expr)
+	if err != nil || value == nil {
+		return reflect.Value{}, err
+	}
+	ch := reflect.ValueOf(value)
+	if ch.Kind() != reflect.Chan {
+		return reflect.Value{}, fmt.Errorf("%s 不是channel", typeName(ch.Type()))
+	}
+	if ch.IsNil() {
+		return reflect.Value{}, nil
+	}
+	return ch, nil
+}
@@ -0,0 +1,252 @@
+package goscript
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGoroutines(t *testing.T) {
+	bindings := map[string]any{
+		"fetch": func(id int) string {
+			time.Sleep(time.Millisecond)
+			return "item" + string(rune('0'+id))
+		},
+	}
+	// 并发调用宿主函数，通过channel收集结果
+	t.Run("Fan Out", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		results := make(chan string, 3)
+		for id := 1; id <= 3; id++ {
+			go func(n int) {
+				results <- fetch(n)
+			}(id)
+		}
+		items := []any{}
+		for n := 0; n < 3; n++ {
+			items = append(items, <-results)
+		}
+		items
+		`)
+		items := result.([]any)
+		got := make([]string, len(items))
+		for idx, item := range items {
+			got[idx] = item.(string)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, []string{"item1", "item2", "item3"}) {
+			t.Errorf("Unexpected result: %v", got)
+		}
+	})
+
+	// 多个goroutine并发执行同一个函数，作用域互不影响
+	t.Run("Concurrent Scopes", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		func sum(from int, to int) int {
+			total := 0
+			for n := from; n <= to; n++ {
+				total += n
+			}
+			return total
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		totals := map[string]any{}
+		for id := 0; id < 20; id++ {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				total := sum(1, n)
+				mu.Lock()
+				totals["" + n] = total
+				mu.Unlock()
+			}(id)
+		}
+		wg.Wait()
+		[]any{len(totals), totals["10"], totals["19"]}
+		`)
+		if !reflect.DeepEqual(result, []any{20, 55, 190}) {
+			t.Errorf("Expected [20 55 190], got %v", result)
+		}
+	})
+
+	// close、comma-ok 接收和 range 遍历channel
+	t.Run("Close And Range", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		ch := make(chan int)
+		go func() {
+			for n := 1; n <= 4; n++ {
+				ch <- n
+			}
+			close(ch)
+		}()
+		total := 0
+		for v := range ch {
+			total += v
+		}
+		v, ok := <-ch
+		[]any{total, v, ok}
+		`)
+		if !reflect.DeepEqual(result, []any{10, 0, false}) {
+			t.Errorf("Expected [10 0 false], got %v", result)
+		}
+	})
+
+	// select 选择就绪的case，都没有就绪时执行default
+	t.Run("Select", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		ch := make(chan string, 1)
+		out := make(chan int, 1)
+		log := []any{}
+		for round := 0; round < 3; round++ {
+			select {
+			case msg := <-ch:
+				log = append(log, msg)
+			case out <- round:
+				log = append(log, "sent")
+			default:
+				log = append(log, "idle")
+			}
+			if round == 0 {
+				ch <- "hello"
+			}
+		}
+		log
+		`)
+		expected := []any{"sent", "hello", "idle"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+
+		result = runScript(t, newTestInterpreter(bindings), `
+		var never chan int
+		select {
+		case <-never:
+			"never"
+		default:
+			"default"
+		}
+		`)
+		if result != nil {
+			t.Errorf("Expected nil, got %v", result)
+		}
+	})
+
+	// 在 select 中使用 break 和带标签的 break
+	t.Run("Select Break", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		ch := make(chan int, 5)
+		for n := 0; n < 5; n++ {
+			ch <- n
+		}
+		close(ch)
+		count := 0
+	loop:
+		for {
+			select {
+			case v, ok := <-ch:
+				if !ok {
+					break loop
+				}
+				if v == 1 {
+					break
+				}
+				count++
+			}
+		}
+		count
+		`)
+		if result != 4 {
+			t.Errorf("Expected 4, got %v", result)
+		}
+	})
+
+	// 向已关闭的channel发送产生panic
+	t.Run("Send On Closed", func(t *testing.T) {
+		_, err := newTestInterpreter(bindings).Interpret(`
+		ch := make(chan int, 1)
+		close(ch)
+		ch <- 1
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Fatalf("Expected *PanicError, got %v", err)
+		}
+		if p.Value != "send on closed channel" || p.Pos.Line != 4 {
+			t.Errorf("Unexpected panic: %+v", p)
+		}
+	})
+
+	// goroutine中未处理的错误交给 OnGoroutineError 设置的处理函数，Fork 出的解释器继承该设置
+	t.Run("Goroutine Errors", func(t *testing.T) {
+		errs := make(chan error, 2)
+		interp := newTestInterpreter(bindings)
+		interp.OnGoroutineError(func(err error) {
+			errs <- err
+		})
+		_, err := interp.Fork().Interpret(`
+		go func() {
+			panic("boom")
+		}()
+		go func() {
+			xs := []int{}
+			xs[1] = 2
+		}()
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		var values []string
+		for n := 0; n < 2; n++ {
+			select {
+			case err := <-errs:
+				var p *PanicError
+				if !errors.As(err, &p) {
+					t.Fatalf("Expected *PanicError, got %v", err)
+				}
+				values = append(values, fmt.Sprint(p.Value))
+			case <-time.After(time.Second):
+				t.Fatal("Expected goroutine error")
+			}
+		}
+		sort.Strings(values)
+		expected := []string{"boom", "runtime error: index out of range [1] with length 0"}
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("Expected %v, got %v", expected, values)
+		}
+	})
+
+	// 结构体字段上的宿主类型调用指针接收器的方法时，使用的是字段本身而不是副本
+	t.Run("Host Fields", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		type Counter struct {
+			mu    sync.Mutex
+			wg    sync.WaitGroup
+			b     strings.Builder
+			total int
+		}
+		c := &Counter{}
+		for n := 1; n <= 10; n++ {
+			c.wg.Add(1)
+			go func(n int) {
+				defer c.wg.Done()
+				c.mu.Lock()
+				c.total += n
+				c.mu.Unlock()
+			}(n)
+		}
+		c.wg.Wait()
+		var s Counter
+		s.b.WriteString("a")
+		s.b.WriteString("b")
+		[]any{c.total, s.b.String(), s.b.Len()}
+		`)
+		if !reflect.DeepEqual(result, []any{55, "ab", 2}) {
+			t.Errorf("Expected [55 ab 2], got %v", result)
+		}
+	})
+}
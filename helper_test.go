@@ -0,0 +1,29 @@
+package goscript
+
+import "testing"
+
+// newTestInterpreter 创建测试用的解释器，bindings 中的值通过 Set 绑定到解释器
+func newTestInterpreter(bindings map[string]any) *Interpreter {
+	interp := NewInterpreter()
+	for name, value := range bindings {
+		interp.Set(name, value)
+	}
+	return interp
+}
+
+// newBackendInterpreter 创建使用指定执行后端的测试解释器
+func newBackendInterpreter(bindings map[string]any, backend Backend) *Interpreter {
+	interp := newTestInterpreter(bindings)
+	interp.SetBackend(backend)
+	return interp
+}
+
+// runScript 在解释器中执行脚本并返回结果，出错时测试失败
+func runScript(t *testing.T, interp *Interpreter, code string) any {
+	t.Helper()
+	result, err := interp.Interpret(code)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return result
+}
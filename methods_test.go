@@ -0,0 +1,174 @@
+package goscript
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMethods(t *testing.T) {
+	// 值接收器和指针接收器
+	t.Run("Receivers", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		type Order struct {
+			Price int
+			Count int
+		}
+
+		func (o Order) Total() int {
+			return o.Price * o.Count
+		}
+
+		func (o *Order) Add(n int) {
+			o.Count = o.Count + n
+		}
+
+		o := Order{Price: 10, Count: 1}
+		o.Add(2)
+		o.Total()
+		`)
+		if result != 30 {
+			t.Errorf("Expected 30, got %v", result)
+		}
+	})
+
+	// 值接收器中的修改不影响调用者
+	t.Run("Value Receiver Copies", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		type Counter struct {
+			n int
+		}
+
+		func (c Counter) Inc() {
+			c.n = c.n + 1
+		}
+
+		c := Counter{}
+		c.Inc()
+		c.n
+		`)
+		if result != 0 {
+			t.Errorf("Expected 0, got %v", result)
+		}
+	})
+
+	// 方法值绑定接收器
+	t.Run("Method Value", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		type Order struct {
+			Price int
+		}
+
+		func (o Order) Double() int {
+			return o.Price * 2
+		}
+
+		o := Order{Price: 4}
+		f := o.Double
+		o.Price = 100
+		f()
+		`)
+		if result != 8 {
+			t.Errorf("Expected 8, got %v", result)
+		}
+	})
+
+	// 通过嵌入字段提升的方法
+	t.Run("Promotion", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		type Base struct {
+			ID int
+		}
+
+		func (b Base) Describe() string {
+			return "base"
+		}
+
+		func (b *Base) SetID(id int) {
+			b.ID = id
+		}
+
+		type Item struct {
+			Base
+			Name string
+		}
+
+		it := Item{Name: "x"}
+		it.SetID(7)
+		it.Describe() + it.Name + it.ID
+		`)
+		if result != "basex7" {
+			t.Errorf("Expected basex7, got %v", result)
+		}
+	})
+
+	// 切片元素和字段上的指针接收器方法会写回
+	t.Run("Write Back", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		type Counter struct {
+			N int
+		}
+
+		func (c *Counter) Inc() {
+			c.N = c.N + 1
+		}
+
+		type Holder struct {
+			C Counter
+		}
+
+		list := []Counter{{}, {}}
+		list[1].Inc()
+		h := Holder{}
+		h.C.Inc()
+		h.C.Inc()
+		list[1].N + h.C.N
+		`)
+		if result != 3 {
+			t.Errorf("Expected 3, got %v", result)
+		}
+	})
+
+	// 方法可以在类型声明之前声明
+	t.Run("Declared Before Type", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		func (p Point) Sum() int {
+			return p.X + p.Y
+		}
+
+		type Point struct {
+			X int
+			Y int
+		}
+
+		Point{X: 1, Y: 2}.Sum()
+		`)
+		if result != 3 {
+			t.Errorf("Expected 3, got %v", result)
+		}
+	})
+
+	// 只能为脚本中的结构体类型声明方法
+	t.Run("Invalid Receiver", func(t *testing.T) {
+		_, err := NewInterpreter().Interpret(`
+		func (s string) Bad() {}
+		1
+		`)
+		if err == nil {
+			t.Fatalf("Expected error")
+		}
+	})
+
+	// 非结构体的命名类型等同于底层类型的别名，不能声明方法
+	t.Run("Named Non-Struct Receiver", func(t *testing.T) {
+		_, err := NewInterpreter().Interpret(`
+		type Celsius float64
+		func (c Celsius) String() string {
+			return "C"
+		}
+		1
+		`)
+		if err == nil || !strings.Contains(err.Error(), "Celsius 的底层类型是 float64") {
+			t.Fatalf("Expected named type error, got %v", err)
+		}
+	})
+}
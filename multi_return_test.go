@@ -0,0 +1,102 @@
+package goscript
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMultiReturn(t *testing.T) {
+	interp := NewInterpreter()
+
+	// 宿主函数的多返回值
+	t.Run("Host Function", func(t *testing.T) {
+		result, err := interp.Interpret(`
+		v, err := strconv.Atoi("42")
+		_, err2 := strconv.Atoi("x")
+		return v, err == nil, err2 != nil
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(result, []any{42, true, true}) {
+			t.Errorf("Expected [42 true true], got %v", result)
+		}
+	})
+
+	// 脚本闭包的多返回值
+	t.Run("Script Closure", func(t *testing.T) {
+		result, err := interp.Interpret(`
+		swap := func(a string, b string) (string, string) {
+			return b, a
+		}
+		var x, y = swap("a", "b")
+		a, b := swap(swap(x, y))
+		return x + y + a + b
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != "baba" {
+			t.Errorf("Expected baba, got %v", result)
+		}
+	})
+
+	// 命名返回值，defer可以修改返回值
+	t.Run("Named Results", func(t *testing.T) {
+		result, err := interp.Interpret(`
+		div := func(a int, b int) (q int, r int) {
+			q = a / b
+			r = a % b
+			return
+		}
+		inc := func() (n int) {
+			defer func() {
+				n = n + 1
+			}()
+			return 10
+		}
+		q, r := div(7, 2)
+		return q, r, inc()
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(result, []any{3, 1, 11}) {
+			t.Errorf("Expected [3 1 11], got %v", result)
+		}
+	})
+
+	// map的comma-ok形式
+	t.Run("Comma Ok", func(t *testing.T) {
+		interp.Set("ages", map[string]int{"tom": 3})
+		result, err := interp.Interpret(`
+		m := map[string]any{"x": 1}
+		v, ok := m["x"]
+		_, ok2 := m["y"]
+		age, ok3 := ages["jerry"]
+		return v, ok, ok2, age, ok3
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(result, []any{1, true, false, 0, false}) {
+			t.Errorf("Expected [1 true false 0 false], got %v", result)
+		}
+	})
+
+	// 数量不匹配时报错
+	t.Run("Arity Mismatch", func(t *testing.T) {
+		cases := []string{
+			`a, b, c := strconv.Atoi("1")`,
+			`a := strconv.Atoi("1")`,
+			`var a, b = 1`,
+			`f := func() (int, int) { return 1 }
+			f()`,
+		}
+		for _, code := range cases {
+			if _, err := interp.Interpret(code); err == nil {
+				t.Errorf("Expected error for %q", code)
+			}
+		}
+	})
+}
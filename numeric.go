@@ -0,0 +1,353 @@
+package goscript
+
+import (
+	"fmt"
+	"go/token"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// 内置的基本类型
+var basicTypes = map[string]reflect.Type{
+	"bool":       reflect.TypeOf(false),
+	"string":     reflect.TypeOf(""),
+	"int":        reflect.TypeOf(int(0)),
+	"int8":       reflect.TypeOf(int8(0)),
+	"int16":      reflect.TypeOf(int16(0)),
+	"int32":      reflect.TypeOf(int32(0)),
+	"int64":      reflect.TypeOf(int64(0)),
+	"uint":       reflect.TypeOf(uint(0)),
+	"uint8":      reflect.TypeOf(uint8(0)),
+	"uint16":     reflect.TypeOf(uint16(0)),
+	"uint32":     reflect.TypeOf(uint32(0)),
+	"uint64":     reflect.TypeOf(uint64(0)),
+	"uintptr":    reflect.TypeOf(uintptr(0)),
+	"float32":    reflect.TypeOf(float32(0)),
+	"float64":    reflect.TypeOf(float64(0)),
+	"complex64":  reflect.TypeOf(complex64(0)),
+	"complex128": reflect.TypeOf(complex128(0)),
+	"byte":       reflect.TypeOf(byte(0)),
+	"rune":       reflect.TypeOf(rune(0)),
+}
+
+var (
+	intType     = basicTypes["int"]
+	float64Type = basicTypes["float64"]
+)
+
+// 解析整数字面量，支持 0x、0o、0b、旧式八进制前缀和数字分隔符 _
+// 超出 int 范围但在 uint64 范围内的字面量为 uint64
+func parseIntLit(lit string) (any, error) {
+	if n, err := strconv.ParseInt(lit, 0, 64); err == nil && n >= math.MinInt && n <= math.MaxInt {
+		return int(n), nil
+	}
+	if n, err := strconv.ParseUint(lit, 0, 64); err == nil {
+		return n, nil
+	}
+	return nil, fmt.Errorf("整数字面量溢出: %s", lit)
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// 是否是数值（整数或浮点数）
+func isNumber(v any) bool {
+	return v != nil && isNumberKind(reflect.TypeOf(v).Kind())
+}
+
+// untypedOperands 标记二元运算中哪些操作数是无类型常量
+type untypedOperands uint8
+
+const (
+	untypedLeft untypedOperands = 1 << iota
+	untypedRight
+)
+
+// 把 int 和 float64 类型的操作数都视为无类型常量，用于无法区分字面量和变量的场合（如泛型的类型推断）
+func defaultUntyped(a, b reflect.Type) untypedOperands {
+	var untyped untypedOperands
+	if a == intType || a == float64Type {
+		untyped |= untypedLeft
+	}
+	if b == intType || b == float64Type {
+		untyped |= untypedRight
+	}
+	return untyped
+}
+
+// 计算两个数值运算的结果类型
+// 脚本中的数值字面量和未声明类型的常量与go中的无类型常量一样，与其它数值类型运算时，
+// 结果为另一个操作数的类型（如 int64 + 1 得到 int64，float32 * 2 得到 float32）；
+// 整数和浮点数运算时结果为浮点数；其它不同类型的变量（如 int 和 uint8、float32 和 float64）之间不能直接运算
+func numericType(a, b reflect.Type, untyped untypedOperands) (reflect.Type, error) {
+	if a == b {
+		return a, nil
+	}
+	aFloat, bFloat := isFloatKind(a.Kind()), isFloatKind(b.Kind())
+	switch {
+	case untyped == untypedLeft|untypedRight:
+		// 两个无类型常量运算时，结果为种类靠后的类型：int、rune、float64
+		if aFloat || (!bFloat && b == intType) {
+			return a, nil
+		}
+		return b, nil
+	case untyped&untypedLeft != 0 && (bFloat || !aFloat):
+		return b, nil
+	case untyped&untypedRight != 0 && (aFloat || !bFloat):
+		return a, nil
+	case aFloat && !bFloat:
+		return a, nil
+	case bFloat && !aFloat:
+		return b, nil
+	}
+	return nil, fmt.Errorf("类型不匹配: %s 和 %s", typeName(a), typeName(b))
+}
+
+// 数值的算术运算，操作数先按 numericType 的规则转换为相同的类型
+// 整数运算按照结果类型的位数回绕，与go一致
+func arith(op token.Token, a, b any, untyped untypedOperands) (any, error) {
+	// 最常见的 int 和 float64 直接计算
+	if x, ok := a.(int); ok {
+		if y, ok := b.(int); ok {
+			return intArith(op, int64(x), int64(y), intType)
+		}
+	}
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			return floatArith(op, x, y, float64Type)
+		}
+	}
+	if !isNumber(a) || !isNumber(b) {
+		return nil, fmt.Errorf("无效操作: %T %s %T", a, op, b)
+	}
+	x, y := reflect.ValueOf(a), reflect.ValueOf(b)
+	t, err := numericType(x.Type(), y.Type(), untyped)
+	if err != nil {
+		return nil, fmt.Errorf("无效操作: %s %s %s: %v", typeName(x.Type()), op, typeName(y.Type()), err)
+	}
+	x, y = x.Convert(t), y.Convert(t)
+	switch {
+	case isIntKind(t.Kind()):
+		return intArith(op, x.Int(), y.Int(), t)
+	case isUintKind(t.Kind()):
+		return uintArith(op, x.Uint(), y.Uint(), t)
+	case isFloatKind(t.Kind()):
+		return floatArith(op, x.Float(), y.Float(), t)
+	}
+	return nil, fmt.Errorf("无效操作: %s %s %s", typeName(x.Type()), op, typeName(y.Type()))
+}
+
+// 将计算结果转换回运算的类型
+func numberOf(value any, t reflect.Type) any {
+	switch t {
+	case intType:
+		if n, ok := value.(int64); ok {
+			return int(n)
+		}
+	case float64Type:
+		if f, ok := value.(float64); ok {
+			return f
+		}
+	}
+	return reflect.ValueOf(value).Convert(t).Interface()
+}
+
+func intArith(op token.Token, x, y int64, t reflect.Type) (any, error) {
+	var r int64
+	switch op {
+	case token.ADD:
+		r = x + y
+	case token.SUB:
+		r = x - y
+	case token.MUL:
+		r = x * y
+	case token.QUO, token.REM:
+		if y == 0 {
+			return nil, runtimeError("integer divide by zero")
+		}
+		if op == token.QUO {
+			r = x / y
+		} else {
+			r = x % y
+		}
+	case token.AND:
+		r = x & y
+	case token.OR:
+		r = x | y
+	case token.XOR:
+		r = x ^ y
+	case token.AND_NOT:
+		r = x &^ y
+	default:
+		return nil, fmt.Errorf("不支持的运算符: %s", op)
+	}
+	return numberOf(r, t), nil
+}
+
+func uintArith(op token.Token, x, y uint64, t reflect.Type) (any, error) {
+	var r uint64
+	switch op {
+	case token.ADD:
+		r = x + y
+	case token.SUB:
+		r = x - y
+	case token.MUL:
+		r = x * y
+	case token.QUO, token.REM:
+		if y == 0 {
+			return nil, runtimeError("integer divide by zero")
+		}
+		if op == token.QUO {
+			r = x / y
+		} else {
+			r = x % y
+		}
+	case token.AND:
+		r = x & y
+	case token.OR:
+		r = x | y
+	case token.XOR:
+		r = x ^ y
+	case token.AND_NOT:
+		r = x &^ y
+	default:
+		return nil, fmt.Errorf("不支持的运算符: %s", op)
+	}
+	return numberOf(r, t), nil
+}
+
+func floatArith(op token.Token, x, y float64, t reflect.Type) (any, error) {
+	var r float64
+	switch op {
+	case token.ADD:
+		r = x + y
+	case token.SUB:
+		r = x - y
+	case token.MUL:
+		r = x * y
+	case token.QUO:
+		if y == 0 {
+			return nil, fmt.Errorf("除以零错误")
+		}
+		r = x / y
+	default:
+		return nil, fmt.Errorf("浮点数不支持运算符: %s", op)
+	}
+	return numberOf(r, t), nil
+}
+
+// 比较两个数值，操作数先按 numericType 的规则转换为相同的类型，不同类型的变量之间不能比较
+func compareNumbers(op token.Token, a, b any, untyped untypedOperands) (bool, error) {
+	x, y := reflect.ValueOf(a), reflect.ValueOf(b)
+	t, err := numericType(x.Type(), y.Type(), untyped)
+	if err != nil {
+		return false, fmt.Errorf("无效操作: %s %s %s: %v", typeName(x.Type()), op, typeName(y.Type()), err)
+	}
+	x, y = x.Convert(t), y.Convert(t)
+	switch {
+	case isIntKind(t.Kind()):
+		return ordered(op, x.Int(), y.Int()), nil
+	case isUintKind(t.Kind()):
+		return ordered(op, x.Uint(), y.Uint()), nil
+	}
+	return ordered(op, x.Float(), y.Float()), nil
+}
+
+// 按比较运算符比较两个相同类型的值，浮点数与go一样按 IEEE 754 比较
+func ordered[T int | int64 | uint64 | float64 | string](op token.Token, x, y T) bool {
+	switch op {
+	case token.LSS:
+		return x < y
+	case token.GTR:
+		return x > y
+	case token.LEQ:
+		return x <= y
+	case token.GEQ:
+		return x >= y
+	case token.EQL:
+		return x == y
+	}
+	return x != y
+}
+
+// 移位运算，结果为左操作数的类型，右操作数必须是非负整数
+func shift(op token.Token, a, b any) (any, error) {
+	if !isNumber(a) || !isNumber(b) || isFloatKind(reflect.TypeOf(a).Kind()) || isFloatKind(reflect.TypeOf(b).Kind()) {
+		return nil, fmt.Errorf("无效操作: %T %s %T", a, op, b)
+	}
+	y := reflect.ValueOf(b)
+	var n uint64
+	if isIntKind(y.Kind()) {
+		if y.Int() < 0 {
+			return nil, fmt.Errorf("移位的位数不能为负数: %d", y.Int())
+		}
+		n = uint64(y.Int())
+	} else {
+		n = y.Uint()
+	}
+	x := reflect.ValueOf(a)
+	if isIntKind(x.Kind()) {
+		if op == token.SHL {
+			return numberOf(x.Int()<<n, x.Type()), nil
+		}
+		return numberOf(x.Int()>>n, x.Type()), nil
+	}
+	v := x.Uint()
+	if op == token.SHL {
+		return numberOf(v<<n, x.Type()), nil
+	}
+	return numberOf(v>>n, x.Type()), nil
+}
+
+// 一元运算 -、+ 和按位取反 ^
+func unaryArith(op token.Token, v any) (any, error) {
+	switch n := v.(type) {
+	case int:
+		switch op {
+		case token.SUB:
+			return -n, nil
+		case token.XOR:
+			return ^n, nil
+		}
+		return n, nil
+	case float64:
+		switch op {
+		case token.SUB:
+			return -n, nil
+		case token.XOR:
+			return nil, fmt.Errorf("浮点数不支持运算符: ^")
+		}
+		return n, nil
+	}
+	if !isNumber(v) {
+		return nil, fmt.Errorf("一元运算 %s 不支持类型: %T", op, v)
+	}
+	if op == token.ADD {
+		return v, nil
+	}
+	x := reflect.ValueOf(v)
+	switch {
+	case isIntKind(x.Kind()) && op == token.XOR:
+		return numberOf(^x.Int(), x.Type()), nil
+	case isIntKind(x.Kind()):
+		return numberOf(-x.Int(), x.Type()), nil
+	case isUintKind(x.Kind()) && op == token.XOR:
+		return numberOf(^x.Uint(), x.Type()), nil
+	case isUintKind(x.Kind()):
+		return numberOf(-x.Uint(), x.Type()), nil
+	case op == token.XOR:
+		return nil, fmt.Errorf("浮点数不支持运算符: ^")
+	default:
+		return numberOf(-x.Float(), x.Type()), nil
+	}
+}
@@ -0,0 +1,187 @@
+package goscript
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type numericHost struct {
+	Count int64
+	Level uint8
+	Ratio float32
+	Size  uint64
+}
+
+func TestNumeric(t *testing.T) {
+	// 各种整数和浮点数字面量
+	t.Run("Literals", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		[]any{0xFF, 0o17, 017, 0b101, 1_000, 1_000.5, 0x1p4, 1e3, 0xFFFFFFFFFFFFFFFF}
+		`)
+		expected := []any{255, 15, 15, 5, 1000, 1000.5, 16.0, 1000.0, uint64(0xFFFFFFFFFFFFFFFF)}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 宿主结构体中的各种数值类型参与运算，脚本中的字面量采用另一个操作数的类型
+	t.Run("Host Kinds", func(t *testing.T) {
+		interp := NewInterpreter()
+		interp.SetGlobal(&numericHost{Count: 10, Level: 250, Ratio: 0.5, Size: 3})
+		result := runScript(t, interp, `
+		[]any{G.Count * 2, G.Level + 10, G.Ratio * 3, G.Size - 1, G.Count > 5, G.Level == 250, G.Size / 2, G.Count % 3, 1.5 * G.Count}
+		`)
+		expected := []any{int64(20), uint8(4), float32(1.5), uint64(2), true, true, uint64(1), int64(1), float64(15)}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 声明的数值类型和零值
+	t.Run("Declared Types", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		var a int64 = 5
+		var b uint64
+		var c float32 = 1
+		var d int8 = 127
+		d++
+		[]any{a / 2, b, c, d, -a}
+		`)
+		expected := []any{int64(2), uint64(0), float32(1), int8(-128), int64(-5)}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 包中的具名数值类型
+	t.Run("Named Types", func(t *testing.T) {
+		interp := NewInterpreter()
+		interp.Set("timeout", 2*time.Second)
+		result := runScript(t, interp, `timeout * 3`)
+		if result != 6*time.Second {
+			t.Errorf("Expected 6s, got %v", result)
+		}
+	})
+
+	// 不同的具名数值类型不能直接运算
+	t.Run("Mismatched Types", func(t *testing.T) {
+		_, err := NewInterpreter().Interpret(`
+		var a int32 = 1
+		var b int64 = 2
+		a + b
+		`)
+		if err == nil {
+			t.Errorf("Expected error")
+		}
+	})
+
+	// 不同类型的变量之间不能比较，无类型常量转换为另一个操作数的类型
+	t.Run("Mismatched Comparisons", func(t *testing.T) {
+		for _, code := range []string{
+			`x := -1; x == uint8(255)`,
+			`x := 256; x > uint8(5)`,
+			`var a int32 = 1; var b int64 = 1; a != b`,
+		} {
+			if _, err := NewInterpreter().Interpret(code); err == nil {
+				t.Errorf("Expected error for %q", code)
+			}
+		}
+		result := runScript(t, NewInterpreter(), `
+		b := uint8(255)
+		[]any{b == 255, b > 5, 2.5 > 1, b < 300/2}
+		`)
+		expected := []any{true, true, true, false}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 与go一样，NaN 与任何值（包括它自己）比较时只有 != 成立
+	t.Run("NaN Comparisons", func(t *testing.T) {
+		code := `
+		var f32 float32 = 1
+		n32 := float32(nan)
+		[]any{nan == nan, nan != nan, nan < 1.0, nan <= 1.0, nan > 1.0, nan >= 1.0, nan == 1.0, n32 == f32, n32 != n32}
+		`
+		expected := []any{false, true, false, false, false, false, false, false, true}
+		for _, backend := range []Backend{BackendTree, BackendClosure, BackendBytecode} {
+			result := runScript(t, newBackendInterpreter(map[string]any{"nan": math.NaN()}, backend), code)
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Backend %d: expected %v, got %v", backend, expected, result)
+			}
+		}
+	})
+
+	// 整数除以零返回错误
+	t.Run("Divide By Zero", func(t *testing.T) {
+		_, err := NewInterpreter().Interpret(`
+		var a uint16 = 1
+		a / 0
+		`)
+		if err == nil {
+			t.Errorf("Expected error")
+		}
+	})
+
+	// 只有字面量和未声明类型的常量是无类型的，不同类型的变量之间不能直接运算
+	t.Run("Untyped Constants", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		const N = 100
+		const F = 0.5
+		var b uint8 = 1
+		var f float32 = 2
+		[]any{b + N, b + (2 * 3), N + b, f * F, -1 + b, 'a' + 1}
+		`)
+		expected := []any{uint8(101), uint8(7), uint8(101), float32(1), uint8(0), int32('b')}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+
+		for _, code := range []string{
+			`x := 300
+			var b uint8 = 1
+			x + b`,
+			`var a float32 = 1
+			var b float64 = 2
+			a + b`,
+			`const M int = 5
+			var b uint8 = 1
+			b + M`,
+			`x := 1
+			var b uint8 = 1
+			b += x`,
+		} {
+			if _, err := NewInterpreter().Interpret(code); err == nil {
+				t.Errorf("Expected error for %q", code)
+			}
+		}
+	})
+
+	// 声明了类型的变量，之后赋的值也转换为声明的类型
+	t.Run("Assign Declared Type", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		var b byte
+		b = 200
+		b += 100
+		var f float32
+		f = 1.5
+		var n int64
+		for i := 0; i < 3; i++ {
+			n += 2
+		}
+		[]any{b, f, n}
+		`)
+		expected := []any{uint8(44), float32(1.5), int64(6)}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+		if _, err := NewInterpreter().Interpret(`
+		var n int
+		n = "str"
+		`); err == nil {
+			t.Errorf("Expected error")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+)
+
+// PanicError 脚本中未被recover的panic，会作为错误从 Interpret 返回
+// 脚本调用 panic 内置函数，或者宿主函数在调用时发生panic，都会产生该错误
+type PanicError struct {
+	// panic 的值
+	Value any
+	// 发生panic的宿主函数在脚本中的名字，脚本自身的panic为空
+	Func string
+	// panic 在脚本中的位置
+	Pos token.Position
+}
+
+func (e *PanicError) Error() string {
+	if e.Func != "" {
+		return fmt.Sprintf("%s: 调用宿主函数 %s 时发生panic: %v", e.Pos, e.Func, e.Value)
+	}
+	return fmt.Sprintf("%s: panic: %v", e.Pos, e.Value)
+}
+
+// Unwrap 如果panic的值是error，返回该error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// 将AST中的位置转换为脚本源码中的位置（去掉包装代码所占的行）
+func (i *Interpreter) position(pos token.Pos) token.Position {
+	if !pos.IsValid() {
+		return token.Position{}
+	}
+	fset := i.fset
+	if fset == nil {
+		fset = i.astCache.fset
+	}
+	p := fset.Position(pos)
+	p.Line -= scriptHeaderLines
+	return p
+}
+
+// 运行时panic（如索引越界、整数除以零），与go一样可以被recover，recover 得到的值是 error
+// 位置由 atPos 在能确定表达式的地方补上
+func runtimeError(format string, args ...any) error {
+	return &PanicError{Value: fmt.Errorf("runtime error: "+format, args...)}
+}
+
+// 给还没有位置的运行时panic加上表达式的位置
+func (i *Interpreter) atPos(err error, pos token.Pos) error {
+	if p, ok := err.(*PanicError); ok && !p.Pos.IsValid() {
+		p.Pos = i.position(pos)
+	}
+	return err
+}
+
+// 将执行语句时发生的go的panic转换为脚本的panic，已经是脚本panic的保持不变
+func (i *Interpreter) runtimePanic(r any, stmt ast.Stmt) *PanicError {
+	if p, ok := r.(*PanicError); ok {
+		return p
+	}
+	p := &PanicError{Value: r}
+	if stmt != nil {
+		p.Pos = i.position(stmt.Pos())
+	}
+	return p
+}
+
+// 调用宿主函数，宿主函数中的panic在调用边界被捕获并转换为 PanicError
+func (i *Interpreter) callHost(call *ast.CallExpr, fn reflect.Value, args []reflect.Value) (results []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if p, ok := r.(*PanicError); ok {
+				// 宿主函数调用的脚本回调中发生的panic
+				results, err = nil, p
+				return
+			}
+			p := &PanicError{Value: r, Func: fn.Type().String()}
+			if call != nil {
+				p.Func = types.ExprString(call.Fun)
+				p.Pos = i.position(call.Pos())
+			}
+			results, err = nil, p
+		}
+	}()
+	return fn.Call(args), nil
+}
+
+// 处理 panic 和 recover 内置函数
+func (i *Interpreter) evalPanicRecover(call *ast.CallExpr, name string) (any, error) {
+	if name == "recover" {
+		if len(call.Args) != 0 {
+			return nil, fmt.Errorf("recover 不接受参数")
+		}
+		return i.recover(), nil
+	}
+	if len(call.Args) != 1 {
+		return nil, fmt.Errorf("panic 需要一个参数")
+	}
+	value, err := i.eval(call.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	return nil, &PanicError{Value: value, Pos: i.position(call.Pos())}
+}
@@ -0,0 +1,201 @@
+package goscript
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPanic(t *testing.T) {
+	interp := NewInterpreter()
+	interp.Set("boom", func(s string) string {
+		panic("boom: " + s)
+	})
+
+	// 未被recover的脚本panic
+	t.Run("Script Panic", func(t *testing.T) {
+		_, err := interp.Interpret(`
+		x := 1
+		panic("bad")
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Fatalf("Expected *PanicError, got %v", err)
+		}
+		if p.Value != "bad" || p.Func != "" {
+			t.Errorf("Unexpected panic: %+v", p)
+		}
+		if p.Pos.Line != 3 {
+			t.Errorf("Expected line 3, got %v", p.Pos)
+		}
+	})
+
+	// 宿主函数的panic转换为错误
+	t.Run("Host Panic", func(t *testing.T) {
+		_, err := interp.Interpret(`
+		print("before")
+		boom("x")
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Fatalf("Expected *PanicError, got %v", err)
+		}
+		if p.Value != "boom: x" || p.Func != "boom" || p.Pos.Line != 3 {
+			t.Errorf("Unexpected panic: %+v", p)
+		}
+	})
+
+	// 在延迟调用中recover
+	t.Run("Recover", func(t *testing.T) {
+		result, err := interp.Interpret(`
+		msg := "none"
+		f := func() {
+			defer func() {
+				if r := recover(); r != nil {
+					msg = "recovered " + r
+				}
+			}()
+			boom("y")
+			msg = "unreachable"
+		}
+		f()
+		msg
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != "recovered boom: y" {
+			t.Errorf("Expected recovered boom: y, got %v", result)
+		}
+	})
+
+	// 不在延迟调用中的recover返回nil
+	t.Run("Recover Outside Defer", func(t *testing.T) {
+		result, err := interp.Interpret(`
+		f := func() {
+			return recover()
+		}
+		f()
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != nil {
+			t.Errorf("Expected nil, got %v", result)
+		}
+	})
+
+	// 延迟调用中的panic替换原来的panic
+	t.Run("Panic In Defer", func(t *testing.T) {
+		_, err := interp.Interpret(`
+		defer func() {
+			panic("second")
+		}()
+		panic("first")
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) || p.Value != "second" {
+			t.Fatalf("Expected second panic, got %v", err)
+		}
+	})
+
+	// 反射赋值失败时返回错误而不是panic
+	t.Run("Bad Field Assignment", func(t *testing.T) {
+		interp := NewInterpreter()
+		interp.SetGlobal(&TestStruct{Name: "a"})
+		_, err := interp.Interpret(`G.Age = "x"`)
+		if err == nil {
+			t.Fatalf("Expected error")
+		}
+		_, err = interp.Interpret(`G.Name = nil`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	})
+
+	// go运行时panic可以被脚本recover
+	t.Run("Recover Runtime Panic", func(t *testing.T) {
+		for _, backend := range []Backend{BackendTree, BackendClosure, BackendBytecode} {
+			interp := NewInterpreter()
+			interp.SetBackend(backend)
+			result, err := interp.Interpret(`
+			var r any
+			func() {
+				defer func() {
+					r = recover()
+				}()
+				var m map[string]int
+				m["a"] = 1
+			}()
+			r.Error()
+			`)
+			if err != nil {
+				t.Fatalf("Error: %v", err)
+			}
+			if result != "assignment to entry in nil map" {
+				t.Errorf("Expected nil map panic, got %v", result)
+			}
+		}
+	})
+
+	// 未被recover的go运行时panic的位置是发生panic的语句
+	t.Run("Runtime Panic Position", func(t *testing.T) {
+		for _, backend := range []Backend{BackendTree, BackendClosure, BackendBytecode} {
+			interp := NewInterpreter()
+			interp.SetBackend(backend)
+			_, err := interp.Interpret(`
+			var m map[string]int
+			m["a"] = 1
+			`)
+			var p *PanicError
+			if !errors.As(err, &p) {
+				t.Fatalf("Expected *PanicError, got %v", err)
+			}
+			if p.Pos.Line != 3 || p.Func != "" {
+				t.Errorf("Expected panic at line 3, got %v", err)
+			}
+		}
+	})
+
+	// 索引越界和整数除以零与go一样是可以被recover的运行时panic
+	t.Run("Index And Divide Panics", func(t *testing.T) {
+		code := `
+		func try(f func()) (r any) {
+			defer func() {
+				r = recover().(error).Error()
+			}()
+			f()
+			return nil
+		}
+		xs := []int{1}
+		zero := 0
+		[]any{
+			try(func() { _ = xs[3] }),
+			try(func() { xs[5] = 1 }),
+			try(func() { _ = 1 / zero }),
+			try(func() { n := 3; n %= zero }),
+		}
+		`
+		expected := []any{
+			"runtime error: index out of range [3] with length 1",
+			"runtime error: index out of range [5] with length 1",
+			"runtime error: integer divide by zero",
+			"runtime error: integer divide by zero",
+		}
+		for _, backend := range []Backend{BackendTree, BackendClosure, BackendBytecode} {
+			result := runScript(t, newBackendInterpreter(nil, backend), code)
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Backend %d: expected %v, got %v", backend, expected, result)
+			}
+
+			_, err := newBackendInterpreter(nil, backend).Interpret(`
+			xs := []int{1}
+			n := xs[1]
+			`)
+			var p *PanicError
+			if !errors.As(err, &p) || p.Pos.Line != 3 {
+				t.Errorf("Backend %d: expected panic at line 3, got %v", backend, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,318 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+)
+
+// varBox 被取过地址的变量
+// 变量第一次被取地址（&x）时，它的值被移到一块新分配的内存中，作用域中保存指向这块内存的 varBox，
+// 这样 &x 得到的指针与变量本身共享同一个值，通过指针的修改对变量可见，反之亦然
+type varBox struct {
+	ptr reflect.Value
+}
+
+// 给装箱的变量赋值，值会转换为变量的类型
+func (b *varBox) set(value any) error {
+	v, err := toValue(value, b.ptr.Type().Elem())
+	if err != nil {
+		return err
+	}
+	b.ptr.Elem().Set(v)
+	return nil
+}
+
+// 读取作用域中保存的值，装箱的变量返回其当前的值
+func unbox(value any) any {
+	if box, ok := value.(*varBox); ok {
+		return box.ptr.Elem().Interface()
+	}
+	return value
+}
+
+// 将变量装箱，返回变量所在的可寻址的内存
+func (i *Interpreter) boxVariable(name string) (reflect.Value, error) {
+	for currentScope := i.scope; currentScope != nil; currentScope = currentScope.parent {
+		value, ok := currentScope.Load(name)
+		if !ok {
+			continue
+		}
+		if box, ok := value.(*varBox); ok {
+			return box.ptr.Elem(), nil
+		}
+		if currentScope.isConst(name) {
+			return reflect.Value{}, fmt.Errorf("无法获取常量 %s 的地址", name)
+		}
+		typ := anyType
+		if value != nil {
+			typ = reflect.TypeOf(value)
+		}
+		ptr := reflect.New(typ)
+		if value != nil {
+			ptr.Elem().Set(reflect.ValueOf(value))
+		}
+		currentScope.Store(name, &varBox{ptr: ptr})
+		return ptr.Elem(), nil
+	}
+
+	// global 对象上的字段
+	if v := reflect.ValueOf(i.global); v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct {
+		if field, ok := v.Elem().Type().FieldByName(name); ok {
+			return fieldByIndex(v.Elem(), field.Index), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("未定义的变量: %s", name)
+}
+
+// 获取表达式所表示的内存，返回可寻址的反射值
+// 支持变量、复合字面量、结构体字段、切片和数组的元素以及 *p
+func (i *Interpreter) addressOf(expr ast.Expr) (reflect.Value, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return i.addressOf(e.X)
+	case *ast.Ident:
+		return i.boxVariable(e.Name)
+	case *ast.CompositeLit:
+		value, err := i.eval(e)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(reflect.TypeOf(value))
+		ptr.Elem().Set(reflect.ValueOf(value))
+		return ptr.Elem(), nil
+	case *ast.StarExpr:
+		p, err := i.eval(e.X)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return i.deref(e, p)
+	case *ast.SelectorExpr:
+		container, err := i.eval(e.X)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.ValueOf(container)
+		switch {
+		case v.Kind() == reflect.Struct:
+			// 结构体值的字段，需要先取得结构体本身的地址
+			if v, err = i.addressOf(e.X); err != nil {
+				return reflect.Value{}, err
+			}
+		case v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct:
+			v = v.Elem()
+		default:
+			return reflect.Value{}, fmt.Errorf("无法获取 %s 的地址", e.Sel.Name)
+		}
+		field, ok := v.Type().FieldByName(e.Sel.Name)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%s 没有字段 %s", typeName(v.Type()), e.Sel.Name)
+		}
+		return fieldByIndex(v, field.Index), nil
+	case *ast.IndexExpr:
+		container, err := i.eval(e.X)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		index, err := i.eval(e.Index)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.ValueOf(container)
+		switch {
+		case v.Kind() == reflect.Array:
+			// 数组值的元素，需要先取得数组本身的地址
+			if v, err = i.addressOf(e.X); err != nil {
+				return reflect.Value{}, err
+			}
+		case v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Array:
+			v = v.Elem()
+		case v.Kind() != reflect.Slice:
+			return reflect.Value{}, fmt.Errorf("无法获取 %T 的元素的地址", container)
+		}
+		n, ok := toInt(index)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("索引必须是整数，得到: %T", index)
+		}
+		if n < 0 || n >= v.Len() {
+			return reflect.Value{}, i.atPos(indexOutOfRange(n, v.Len()), e.Pos())
+		}
+		return v.Index(n), nil
+	}
+	return reflect.Value{}, fmt.Errorf("无法获取表达式的地址: %T", expr)
+}
+
+// 处理取地址表达式 &x
+func (i *Interpreter) evalAddressOf(expr ast.Expr) (any, error) {
+	v, err := i.addressOf(expr)
+	if err != nil {
+		return nil, err
+	}
+	return v.Addr().Interface(), nil
+}
+
+// 指针指向的内存，nil 指针产生可以被recover的panic
+func (i *Interpreter) deref(expr ast.Expr, p any) (reflect.Value, error) {
+	v := reflect.ValueOf(p)
+	if p == nil || v.Kind() == reflect.Ptr && v.IsNil() {
+		return reflect.Value{}, i.atPos(runtimeError("invalid memory address or nil pointer dereference"), expr.Pos())
+	}
+	if v.Kind() != reflect.Ptr {
+		return reflect.Value{}, fmt.Errorf("无效的解引用: %T 不是指针", p)
+	}
+	return v.Elem(), nil
+}
+
+// 处理解引用表达式 *p
+func (i *Interpreter) evalStarExpr(expr *ast.StarExpr) (any, error) {
+	p, err := i.eval(expr.X)
+	if err != nil {
+		return nil, err
+	}
+	v, err := i.deref(expr, p)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// 通过指针赋值 *p = value
+func (i *Interpreter) assignStar(expr *ast.StarExpr, value any) error {
+	p, err := i.eval(expr.X)
+	if err != nil {
+		return err
+	}
+	return i.storePointee(expr, p, value)
+}
+
+// 通过已求值的指针赋值
+func (i *Interpreter) storePointee(expr *ast.StarExpr, p any, value any) error {
+	v, err := i.deref(expr, p)
+	if err != nil {
+		return err
+	}
+	converted, err := toValue(value, v.Type())
+	if err != nil {
+		return err
+	}
+	v.Set(converted)
+	return nil
+}
+
+// 将脚本中的参数转换为宿主函数的参数类型
+// 参数需要指针而传入的是值时，可寻址的参数（如变量）传递它的地址，这样宿主函数的修改对脚本可见；
+// 参数需要值而传入的是指针时，传递指针指向的值
+func (i *Interpreter) hostArg(expr ast.Expr, arg any, paramType reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Zero(paramType), nil
+	}
+	if fn, ok := arg.(reflect.Value); ok && fn.IsValid() {
+		// 绑定的宿主函数
+		arg = fn.Interface()
+	}
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(paramType) {
+		return v, nil
+	}
+	if paramType.Kind() == reflect.Ptr && v.Type().AssignableTo(paramType.Elem()) {
+		if expr != nil && isAddressable(expr) {
+			if addr, err := i.addressOf(expr); err == nil && addr.Type() == paramType.Elem() {
+				return addr.Addr(), nil
+			}
+		}
+		ptr := reflect.New(paramType.Elem())
+		ptr.Elem().Set(v)
+		return ptr, nil
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type().AssignableTo(paramType) {
+		return v.Elem(), nil
+	}
+	if converted, err := toValue(arg, paramType); err == nil {
+		return converted, nil
+	}
+	if v.Type().ConvertibleTo(paramType) {
+		return v.Convert(paramType), nil
+	}
+	return v, nil
+}
+
+// 方法是否只在宿主类型的指针方法集中，如 sync.Mutex 的 Lock
+func hostPointerMethod(t reflect.Type, name string) bool {
+	if t == nil || t.Kind() == reflect.Ptr {
+		return false
+	}
+	if _, ok := t.MethodByName(name); ok {
+		return false
+	}
+	_, ok := reflect.PtrTo(t).MethodByName(name)
+	return ok
+}
+
+// 调用方法 name 的接收器 sel.X。接收器是结构体字段中保存的宿主类型的值，且方法只在指针方法集中时（如 mu sync.Mutex 的 Lock），
+// 返回字段的地址，方法作用在字段本身而不是副本上；通过结构体指针访问字段时不复制字段的值，并发调用 Lock 等方法是安全的
+// 接收器是变量时（如 var mu sync.Mutex），返回变量的地址
+func (i *Interpreter) methodReceiver(sel *ast.SelectorExpr) (any, error) {
+	if ident, ok := sel.X.(*ast.Ident); ok {
+		return i.variableReceiver(ident, sel.Sel.Name)
+	}
+	field, ok := sel.X.(*ast.SelectorExpr)
+	if !ok {
+		return i.eval(sel.X)
+	}
+	container, err := i.eval(field.X)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(container)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		if f, ok := v.Type().FieldByName(field.Sel.Name); ok && hostPointerMethod(f.Type, sel.Sel.Name) {
+			if v.CanAddr() {
+				return fieldByIndex(v, f.Index).Addr().Interface(), nil
+			}
+			// 结构体值的字段，需要先取得结构体本身的地址
+			if addr, err := i.addressOf(field); err == nil {
+				return addr.Addr().Interface(), nil
+			}
+		}
+	}
+	return i.selectValue(field, container)
+}
+
+// 变量作为调用方法 name 的接收器，方法只在宿主类型的指针方法集中时返回变量的地址
+// 已经装箱的变量不读取它的值，并发调用 Lock 等方法是安全的
+func (i *Interpreter) variableReceiver(ident *ast.Ident, name string) (value any, err error) {
+	value, found := i.load(ident.Name)
+	if box, ok := value.(*varBox); ok {
+		if hostPointerMethod(box.ptr.Type().Elem(), name) {
+			return box.ptr.Interface(), nil
+		}
+		value = unbox(box)
+	} else if !found {
+		// global 对象上的属性和未定义的标识符
+		if value, err = i.eval(ident); err != nil {
+			return nil, err
+		}
+	}
+	if hostPointerMethod(reflect.TypeOf(value), name) {
+		if addr, err := i.addressOf(ident); err == nil && addr.Type() == reflect.TypeOf(value) {
+			return addr.Addr().Interface(), nil
+		}
+	}
+	return value, nil
+}
+
+// 求值 defer 和 go 语句调用的函数，方法的接收器与直接调用时相同，通过 methodReceiver 求值
+func (i *Interpreter) evalCallee(fun ast.Expr) (any, error) {
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return i.eval(fun)
+	}
+	container, err := i.methodReceiver(sel)
+	if err != nil {
+		return nil, err
+	}
+	return i.selectValue(sel, container)
+}
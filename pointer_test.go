@@ -0,0 +1,190 @@
+package goscript
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPointer(t *testing.T) {
+	bindings := map[string]any{
+		"json": map[string]any{
+			"Unmarshal": json.Unmarshal,
+		},
+		"mapJSON":    []byte(`{"a": 1}`),
+		"configJSON": []byte(`{"Name": "svc", "Ports": [80, 443]}`),
+		"rename": func(p *Person, name string) {
+			p.Name = name
+		},
+		"describe": func(p Person) string {
+			return p.Name
+		},
+	}
+	// 变量的地址与变量共享同一个值
+	t.Run("Variables", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		x := 1
+		p := &x
+		*p = 10
+		y := *p + 1
+		x += 5
+		q := &x
+		[]any{x, y, *p, *q, p == q}
+		`)
+		expected := []any{15, 11, 15, 15, true}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 复合字面量和字段的地址
+	t.Run("Composite Literals And Fields", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		type Point struct {
+			X int
+			Y int
+		}
+		p := &Point{X: 1, Y: 2}
+		p.X = 5
+		py := &p.Y
+		*py = 7
+		v := Point{}
+		vx := &v.X
+		*vx = 3
+		list := []any{1, 2}
+		first := &list[0]
+		*first = 100
+		[]any{p.X, p.Y, (*p).X, v.X, list[0]}
+		`)
+		expected := []any{5, 7, 5, 3, 100}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 指针接收器的方法和方法值作用于变量本身
+	t.Run("Pointer Receivers", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		type Counter struct {
+			N int
+		}
+
+		func (c *Counter) Inc() {
+			c.N++
+		}
+
+		c := Counter{}
+		p := &c
+		p.Inc()
+		c.Inc()
+		inc := c.Inc
+		inc()
+		[]any{c.N, p.N}
+		`)
+		if !reflect.DeepEqual(result, []any{3, 3}) {
+			t.Errorf("Expected [3 3], got %v", result)
+		}
+	})
+
+	// 宿主函数的指针参数
+	t.Run("Host Pointer Params", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		type Config struct {
+			Name  string
+			Ports []int
+		}
+		var m map[string]any
+		err := json.Unmarshal(mapJSON, &m)
+		var cfg Config
+		json.Unmarshal(configJSON, &cfg)
+		[]any{err, m["a"], cfg.Name, cfg.Ports}
+		`)
+		expected := []any{nil, 1.0, "svc", []int{80, 443}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 需要指针的宿主函数传入变量时传递变量的地址，需要值时传入指针会解引用
+	t.Run("Automatic Address", func(t *testing.T) {
+		interp := newTestInterpreter(bindings)
+		name := "李四"
+		interp.Set("person", Person{Name: "张三", Name2: &name})
+		result := runScript(t, interp, `
+		p := person
+		rename(p, "王五")
+		ptr := &p
+		*p.Name2 = "赵六"
+		[]any{p.Name, describe(ptr), *person.Name2}
+		`)
+		expected := []any{"王五", "王五", "赵六"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+		if name != "赵六" {
+			t.Errorf("Expected host variable to be updated, got %v", name)
+		}
+	})
+
+	// nil 指针解引用产生可以recover的panic
+	t.Run("Nil Dereference", func(t *testing.T) {
+		_, err := newTestInterpreter(bindings).Interpret(`
+		var p *int
+		x := *p
+		`)
+		var pe *PanicError
+		if !errors.As(err, &pe) {
+			t.Fatalf("Expected *PanicError, got %v", err)
+		}
+		if pe.Pos.Line != 3 {
+			t.Errorf("Expected line 3, got %v", pe.Pos)
+		}
+	})
+
+	// && 和 || 短路求值，nil 检查可以保护后面的解引用
+	t.Run("Nil Guard", func(t *testing.T) {
+		code := `
+		var p *int
+		n := 1
+		q := &n
+		calls := 0
+		count := func() bool {
+			calls++
+			return true
+		}
+		[]any{p != nil && *p > 0, p == nil || *p > 0, q != nil && *q > 0, false && count(), true || count(), calls}
+		`
+		expected := []any{false, true, true, false, true, 0}
+		for _, backend := range []Backend{BackendTree, BackendClosure, BackendBytecode} {
+			result := runScript(t, newBackendInterpreter(bindings, backend), code)
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Backend %d: expected %v, got %v", backend, expected, result)
+			}
+		}
+	})
+
+	// 宿主结构体类型的变量保存的是结构体本身，&sb 是 *strings.Builder，指针接收器的方法作用在变量上
+	t.Run("Host Struct Variable", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		var sb strings.Builder
+		p := &sb
+		p.WriteString("a")
+		sb.WriteString("b")
+		write := sb.WriteString
+		write("c")
+		[]any{sb.String(), p, sb}
+		`)
+		values := result.([]any)
+		if values[0] != "abc" {
+			t.Errorf("Expected abc, got %v", values[0])
+		}
+		if p, ok := values[1].(*strings.Builder); !ok || p.String() != "abc" {
+			t.Errorf("Expected *strings.Builder, got %T", values[1])
+		}
+		if _, ok := values[2].(strings.Builder); !ok {
+			t.Errorf("Expected strings.Builder, got %T", values[2])
+		}
+	})
+}
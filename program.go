@@ -0,0 +1,161 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sync"
+)
+
+// Program 是编译后的脚本，预处理和解析只在编译时进行一次
+// Program 只读，可以在多个解释器和goroutine中重复执行
+type Program struct {
+	fset *token.FileSet
+	// 类型声明，按脚本中的顺序
+	types []*ast.TypeSpec
+	// 函数、泛型函数和方法声明
+	funcs []*ast.FuncDecl
+	main  *ast.FuncDecl
+	// Program.Run 使用的解释器，编译时创建一次，每次执行时 Fork
+	base *Interpreter
+
+	// 编译为闭包的语句，第一次使用 BackendClosure 执行时生成
+	closureOnce sync.Once
+	closures    *closureProgram
+	// 编译后的字节码，第一次使用 BackendBytecode 执行或反汇编时生成
+	bytecodeOnce sync.Once
+	chunks       *bytecodeProgram
+}
+
+// Compile 编译脚本，脚本有语法错误时返回错误
+func Compile(code string) (*Program, error) {
+	prog, err := compile(token.NewFileSet(), code)
+	if err != nil {
+		return nil, err
+	}
+	prog.base = NewInterpreter()
+	return prog, nil
+}
+
+func compile(fset *token.FileSet, code string) (*Program, error) {
+	// 预处理单引号字符串
+	astFile, err := parseScript(fset, wrapScript(preprocessSingleQuoteString(code)))
+	if err != nil {
+		return nil, err
+	}
+	prog := &Program{fset: fset}
+	for _, decl := range astFile.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+					prog.types = append(prog.types, typeSpec)
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Name.Name == "__main__" {
+				prog.main = decl
+				continue
+			}
+			prog.funcs = append(prog.funcs, decl)
+		}
+	}
+	if prog.main == nil {
+		return nil, fmt.Errorf("脚本缺少主体")
+	}
+	return prog, nil
+}
+
+// RunOption Program.Run 的选项
+type RunOption func(interp *Interpreter)
+
+// WithBackend 指定执行程序的方式
+func WithBackend(backend Backend) RunOption {
+	return func(interp *Interpreter) {
+		interp.SetBackend(backend)
+	}
+}
+
+// Run 在编译时创建的解释器 Fork 出的解释器中执行程序，env 中的值在执行前绑定到解释器
+// 每次执行的变量互不影响，可以并发执行
+func (p *Program) Run(env map[string]any, opts ...RunOption) (any, error) {
+	interp := p.base.Fork()
+	for _, opt := range opts {
+		opt(interp)
+	}
+	for name, value := range env {
+		interp.Set(name, value)
+	}
+	return interp.Run(p)
+}
+
+// Run 在解释器中执行编译好的程序，脚本中的类型和函数定义在解释器的作用域中，执行结束后可以通过 Call 调用
+// 同一个程序可以在多个 Fork 出的解释器中并发执行
+func (i *Interpreter) Run(prog *Program) (result any, err error) {
+	// 在副本上执行，源码位置使用程序自己的FileSet
+	run := *i
+	run.fset = prog.fset
+	switch run.backend {
+	case BackendClosure:
+		closures := prog.closureCode()
+		run.compiled, run.layouts = closures.code, closures.layouts
+	case BackendBytecode:
+		run.compiled, run.layouts = prog.bytecode().code, nil
+	default:
+		run.compiled, run.layouts = nil, nil
+	}
+	i = &run
+	// 兜底：解释器自身的panic不应导致宿主进程崩溃
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &PanicError{Value: r}
+		}
+	}()
+
+	// 首先处理所有类型和函数定义，函数在脚本主体执行前就可以调用，执行结束后宿主也可以通过 Call 调用
+	for _, typeSpec := range prog.types {
+		if err := i.evalTypeSpec(typeSpec); err != nil {
+			return nil, err
+		}
+	}
+	// 方法的接收器类型可能在方法之后才声明，所以在类型之后处理函数
+	// 函数和方法保存在解释器的作用域中，函数体可以访问脚本主体的变量
+	mainScope := newScope(i.scope, i.layouts[prog.main.Body])
+	for _, decl := range prog.funcs {
+		if decl.Recv != nil {
+			if err := i.declareMethod(decl, mainScope); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if decl.Type.TypeParams != nil {
+			i.scope.Store(decl.Name.Name, i.withScope(mainScope).newGeneric(decl))
+			continue
+		}
+		i.scope.Store(decl.Name.Name, i.withScope(mainScope).newFunction(decl.Type, decl.Body))
+	}
+
+	// 执行 __main__ 函数
+	result, err = i.evalFuncBody(prog.main.Body, mainScope, nil, nil)
+	if t, ok := result.(tuple); ok {
+		// 多返回值以 []any 的形式返回给宿主
+		result = []any(t)
+	}
+	return result, err
+}
+
+// 编译后的闭包，多次执行和并发执行时共用
+func (p *Program) closureCode() *closureProgram {
+	p.closureOnce.Do(func() {
+		p.closures = compileClosures(p)
+	})
+	return p.closures
+}
+
+// 编译后的字节码，多次执行和并发执行时共用
+func (p *Program) bytecode() *bytecodeProgram {
+	p.bytecodeOnce.Do(func() {
+		p.chunks = compileBytecode(p)
+	})
+	return p.chunks
+}
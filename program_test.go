@@ -0,0 +1,140 @@
+package goscript
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestProgram(t *testing.T) {
+	prog, err := Compile(`
+	type Greeting struct {
+		Name string
+	}
+
+	func greet(name string) string {
+		g := Greeting{Name: name}
+		return prefix + g.Name
+	}
+
+	greet(user)
+	`)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+
+	// 编译一次，使用不同的环境执行多次
+	t.Run("Run With Env", func(t *testing.T) {
+		for _, user := range []string{"alice", "bob"} {
+			result, err := prog.Run(map[string]any{"prefix": "hello ", "user": user})
+			if err != nil {
+				t.Fatalf("Error: %v", err)
+			}
+			if result != "hello "+user {
+				t.Errorf("Expected hello %s, got %v", user, result)
+			}
+		}
+	})
+
+	// 通过选项指定执行方式，每次执行使用编译时创建的解释器 Fork 出的解释器
+	t.Run("Run With Backend", func(t *testing.T) {
+		prog, err := Compile(`
+		func greet(name string) string {
+			return prefix + name
+		}
+		greet(user)
+		`)
+		if err != nil {
+			t.Fatalf("Compile error: %v", err)
+		}
+		for _, backend := range []Backend{BackendTree, BackendClosure, BackendBytecode} {
+			result, err := prog.Run(map[string]any{"prefix": "hello ", "user": "erin"}, WithBackend(backend))
+			if err != nil {
+				t.Fatalf("%v: Error: %v", backend, err)
+			}
+			if result != "hello erin" {
+				t.Errorf("%v: Expected hello erin, got %v", backend, result)
+			}
+		}
+		if prog.closures == nil || prog.chunks == nil {
+			t.Error("Expected the program to be compiled for each backend")
+		}
+		if _, ok := prog.base.scope.Load("greet"); ok {
+			t.Error("Expected runs not to define functions in the base interpreter")
+		}
+	})
+
+	// 在 Fork 出的解释器中并发执行同一个程序
+	t.Run("Concurrent Forks", func(t *testing.T) {
+		interp := NewInterpreter()
+		interp.Set("prefix", "hi ")
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for n := 0; n < 10; n++ {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				fork := interp.Fork()
+				user := fmt.Sprint("user", n)
+				fork.Set("user", user)
+				result, err := fork.Run(prog)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if result != "hi "+user {
+					errs <- fmt.Errorf("expected hi %s, got %v", user, result)
+				}
+			}(n)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+	})
+
+	// 执行后可以调用脚本中定义的函数
+	t.Run("Call After Run", func(t *testing.T) {
+		interp := NewInterpreter()
+		interp.Set("prefix", "hey ")
+		interp.Set("user", "carol")
+		if _, err := interp.Run(prog); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		result, err := interp.Call("greet", "dave")
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != "hey dave" {
+			t.Errorf("Expected hey dave, got %v", result)
+		}
+	})
+
+	// 语法错误在编译时返回
+	t.Run("Compile Error", func(t *testing.T) {
+		if _, err := Compile(`x := `); err == nil {
+			t.Fatal("Expected compile error")
+		}
+	})
+
+	// 编译后的程序中panic的位置是脚本中的行号
+	t.Run("Panic Position", func(t *testing.T) {
+		prog, err := Compile(`
+		x := 1
+		panic("bad")
+		`)
+		if err != nil {
+			t.Fatalf("Compile error: %v", err)
+		}
+		_, err = prog.Run(nil)
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Fatalf("Expected *PanicError, got %v", err)
+		}
+		if p.Value != "bad" || p.Pos.Line != 3 {
+			t.Errorf("Unexpected panic: %+v", p)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"unicode/utf8"
+)
+
+// 处理 range 语句，支持切片、数组、map、字符串、整数、channel 和迭代器函数
+// 使用 := 时每次迭代都有自己的变量，闭包捕获的是当次迭代的值
+func (i *Interpreter) evalRangeStmt(node *ast.RangeStmt, label string) (any, error) {
+	// 获取要遍历的值
+	val, err := i.eval(node.X)
+	if err != nil {
+		return nil, err
+	}
+	return i.rangeValue(node, val, label)
+}
+
+// 遍历已求值的 range 表达式的值，返回循环体产生的需要继续向外传递的控制流信号
+func (i *Interpreter) rangeValue(node *ast.RangeStmt, val any, label string) (any, error) {
+	// 执行一次迭代，返回 false 时停止遍历
+	var signal any
+	body := func(key, value any) (bool, error) {
+		iter := i.withScope(&Scope{parent: i.scope})
+		if err := iter.bindRangeVar(node.Key, key, node.Tok); err != nil {
+			return false, err
+		}
+		if err := iter.bindRangeVar(node.Value, value, node.Tok); err != nil {
+			return false, err
+		}
+		result, err := iter.eval(node.Body)
+		if err != nil {
+			return false, err
+		}
+		// 处理 break、continue 和 return
+		if exit, s := loopControl(result, label); exit {
+			signal = s
+			return false, nil
+		}
+		return true, nil
+	}
+
+	if err := i.rangeOver(node, val, body); err != nil {
+		return nil, err
+	}
+	return signal, nil
+}
+
+// 绑定 range 的变量，:= 时在当次迭代的作用域中定义，= 时赋值给已有的变量
+func (i *Interpreter) bindRangeVar(expr ast.Expr, value any, tok token.Token) error {
+	if expr == nil {
+		return nil
+	}
+	if ident, ok := expr.(*ast.Ident); ok && ident.Name == "_" {
+		return nil
+	}
+	return i.assign(expr, value, tok == token.DEFINE)
+}
+
+// 依次将被遍历的值的每个元素交给 body
+func (i *Interpreter) rangeOver(node *ast.RangeStmt, val any, body func(key, value any) (bool, error)) error {
+	if val == nil {
+		// nil 切片或map，没有元素
+		return nil
+	}
+	if fn, ok := val.(func(...any) (any, error)); ok {
+		return i.rangeScriptFunc(fn, body)
+	}
+
+	rval, ok := val.(reflect.Value)
+	if !ok {
+		rval = reflect.ValueOf(val)
+	}
+	if rval.Kind() == reflect.Ptr && rval.Elem().Kind() == reflect.Array {
+		// 数组指针
+		rval = rval.Elem()
+	}
+	switch rval.Kind() {
+	case reflect.Slice, reflect.Array:
+		// 遍历切片或数组
+		for n := 0; n < rval.Len(); n++ {
+			if next, err := body(n, rval.Index(n).Interface()); !next || err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		// 遍历 map
+		iter := rval.MapRange()
+		for iter.Next() {
+			if next, err := body(iter.Key().Interface(), iter.Value().Interface()); !next || err != nil {
+				return err
+			}
+		}
+
+	case reflect.String:
+		// 遍历字符串中的rune，索引是rune在字符串中的字节位置
+		s := rval.String()
+		for idx := 0; idx < len(s); {
+			r, size := utf8.DecodeRuneInString(s[idx:])
+			if next, err := body(idx, r); !next || err != nil {
+				return err
+			}
+			idx += size
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// 遍历 0 到 n-1，变量的类型与 n 相同
+		if node.Value != nil {
+			return fmt.Errorf("range 遍历整数时只能有一个变量")
+		}
+		n, _ := toInt(val)
+		for idx := 0; idx < n; idx++ {
+			if next, err := body(reflect.ValueOf(idx).Convert(rval.Type()).Interface(), nil); !next || err != nil {
+				return err
+			}
+		}
+
+	case reflect.Chan:
+		// 遍历channel直到channel被关闭
+		if node.Value != nil {
+			return fmt.Errorf("range 遍历channel时只能有一个变量")
+		}
+		for {
+			item, ok := rval.Recv()
+			if !ok {
+				return nil
+			}
+			if next, err := body(item.Interface(), nil); !next || err != nil {
+				return err
+			}
+		}
+
+	case reflect.Func:
+		return i.rangeHostFunc(node, rval, body)
+
+	default:
+		return fmt.Errorf("range: cannot range over %v (type %T)", val, val)
+	}
+	return nil
+}
+
+// 遍历宿主提供的迭代器函数，如 func(yield func(K, V) bool)
+func (i *Interpreter) rangeHostFunc(node *ast.RangeStmt, fn reflect.Value, body func(key, value any) (bool, error)) error {
+	fnType := fn.Type()
+	if fnType.NumIn() != 1 || fnType.NumOut() != 0 {
+		return fmt.Errorf("range: cannot range over %s", typeName(fnType))
+	}
+	yieldType := fnType.In(0)
+	if yieldType.Kind() != reflect.Func || yieldType.NumIn() > 2 || yieldType.NumOut() != 1 || yieldType.Out(0).Kind() != reflect.Bool {
+		return fmt.Errorf("range: cannot range over %s", typeName(fnType))
+	}
+	if node.Key != nil && yieldType.NumIn() == 0 || node.Value != nil && yieldType.NumIn() < 2 {
+		return fmt.Errorf("range 的变量数量超过了迭代器 %s 产生的值的数量", typeName(fnType))
+	}
+
+	// 循环体出错或退出后，yield 返回 false 通知迭代器停止
+	var bodyErr error
+	done := false
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		if done {
+			return []reflect.Value{reflect.ValueOf(false)}
+		}
+		values := make([]any, 2)
+		for idx, arg := range args {
+			values[idx] = arg.Interface()
+		}
+		next, err := body(values[0], values[1])
+		if err != nil || !next {
+			bodyErr, done = err, true
+		}
+		return []reflect.Value{reflect.ValueOf(next && err == nil)}
+	})
+	if _, err := i.callHost(nil, fn, []reflect.Value{yield}); err != nil {
+		return err
+	}
+	return bodyErr
+}
+
+// 遍历脚本中定义的迭代器函数，yield 的参数依次作为 range 的变量
+func (i *Interpreter) rangeScriptFunc(fn func(...any) (any, error), body func(key, value any) (bool, error)) error {
+	var bodyErr error
+	done := false
+	yield := func(args ...any) (any, error) {
+		_, args = callerOf(args)
+		if done {
+			return false, nil
+		}
+		if len(args) > 2 {
+			return nil, fmt.Errorf("yield 最多只能有两个参数")
+		}
+		values := make([]any, 2)
+		copy(values, args)
+		next, err := body(values[0], values[1])
+		if err != nil || !next {
+			bodyErr, done = err, true
+		}
+		return next && err == nil, nil
+	}
+	if _, err := fn(withCaller(i.frame, []any{yield})...); err != nil {
+		return err
+	}
+	return bodyErr
+}
@@ -0,0 +1,182 @@
+package goscript
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	bindings := map[string]any{
+		"pairs": func(yield func(string, int) bool) {
+			for idx, name := range []string{"a", "b", "c"} {
+				if !yield(name, idx) {
+					return
+				}
+			}
+		},
+		"naturals": func(yield func(int) bool) {
+			for n := 0; ; n++ {
+				if !yield(n) {
+					return
+				}
+			}
+		},
+		"fail": func() error {
+			return errors.New("failed")
+		},
+	}
+	// 遍历整数，变量的类型与整数相同
+	t.Run("Integers", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		total := 0
+		for n := range 5 {
+			total += n
+		}
+		count := 0
+		for range 3 {
+			count++
+		}
+		var three int64 = 3
+		var last int64
+		for n := range three {
+			last = n
+		}
+		[]any{total, count, last}
+		`)
+		if !reflect.DeepEqual(result, []any{10, 3, int64(2)}) {
+			t.Errorf("Expected [10 3 2], got %v", result)
+		}
+	})
+
+	// 遍历字符串得到rune和字节位置
+	t.Run("Strings", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		indexes := []any{}
+		runes := []any{}
+		for idx, r := range "a中b" {
+			indexes = append(indexes, idx)
+			runes = append(runes, r)
+		}
+		[]any{indexes, runes}
+		`)
+		expected := []any{[]any{0, 1, 4}, []any{'a', '中', 'b'}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 每次迭代都有自己的变量
+	t.Run("Per Iteration Variables", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		funcs := []any{}
+		for _, v := range []any{1, 2, 3} {
+			funcs = append(funcs, func() int {
+				return v
+			})
+		}
+		values := []any{}
+		for _, f := range funcs {
+			values = append(values, f())
+		}
+		values
+		`)
+		if !reflect.DeepEqual(result, []any{1, 2, 3}) {
+			t.Errorf("Expected [1 2 3], got %v", result)
+		}
+	})
+
+	// 使用 = 赋值给已有的变量
+	t.Run("Assign", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		key := ""
+		value := 0
+		for key, value = range map[string]any{"x": 7} {
+		}
+		list := []any{0, 0}
+		for list[0] = range []any{"a", "b"} {
+		}
+		[]any{key, value, list[0]}
+		`)
+		if !reflect.DeepEqual(result, []any{"x", 7, 1}) {
+			t.Errorf("Expected [x 7 1], got %v", result)
+		}
+	})
+
+	// 遍历宿主提供的迭代器函数，break 会停止迭代器
+	t.Run("Host Iterators", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		names := ""
+		for name, idx := range pairs {
+			names += name + idx
+		}
+		sum := 0
+		for n := range naturals {
+			if n > 4 {
+				break
+			}
+			sum += n
+		}
+		[]any{names, sum}
+		`)
+		if !reflect.DeepEqual(result, []any{"a0b1c2", 10}) {
+			t.Errorf("Expected [a0b1c2 10], got %v", result)
+		}
+	})
+
+	// 遍历脚本中定义的迭代器函数，循环体中的 return 从外层函数返回
+	t.Run("Script Iterators", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		func countdown(n int) any {
+			return func(yield func(int) bool) {
+				for ; n > 0; n-- {
+					if !yield(n) {
+						return
+					}
+				}
+			}
+		}
+
+		func firstBelow(limit int) int {
+			for n := range countdown(10) {
+				if n < limit {
+					return n
+				}
+			}
+			return -1
+		}
+
+		values := []any{}
+		for n := range countdown(3) {
+			values = append(values, n)
+		}
+		[]any{values, firstBelow(5)}
+		`)
+		expected := []any{[]any{3, 2, 1}, 4}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 循环体中的错误会停止迭代器并返回
+	t.Run("Errors", func(t *testing.T) {
+		_, err := newTestInterpreter(bindings).Interpret(`
+		for n := range naturals {
+			if n == 2 {
+				panic(fail())
+			}
+		}
+		`)
+		var p *PanicError
+		if !errors.As(err, &p) || p.Pos.Line != 4 {
+			t.Fatalf("Expected *PanicError at line 4, got %v", err)
+		}
+
+		if _, err := newTestInterpreter(bindings).Interpret(`
+		for a, b := range 3 {
+		}
+		`); err == nil {
+			t.Errorf("Expected error")
+		}
+	})
+}
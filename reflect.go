@@ -108,7 +108,8 @@ func (r *reflectCache) cacheFields(item *reflectCacheItem, t reflect.Type) {
 			field := t.Field(i)
 			index := append(append([]int{}, parentIndex...), i)
 
-			if field.IsExported() || field.Anonymous {
+			// 脚本中定义的结构体的未导出字段对脚本可见
+			if field.IsExported() || field.Anonymous || field.PkgPath == scriptPkgPath && field.Name != "_" {
 				item.fields[field.Name] = fieldInfo{
 					index: index,
 					typ:   field.Type,
@@ -208,7 +209,7 @@ func (r *reflectCache) getValue(item *reflectCacheItem, obj any, fieldName strin
 		if v.Kind() == reflect.Ptr {
 			v = v.Elem()
 		}
-		return v.FieldByIndex(field.index).Interface(), field.typ
+		return fieldByIndex(v, field.index).Interface(), field.typ
 	}
 	return nil, nil
 }
@@ -250,8 +251,8 @@ func (r *reflectCache) get(obj any, name string) (any, reflect.Type) {
 	if item == nil {
 		return nil, nil
 	}
-	if field, typ := r.getValue(item, obj, name); field != nil {
-		return field, typ
+	if _, ok := item.fields[name]; ok {
+		return r.getValue(item, obj, name)
 	}
 	if method, typ := r.getMethod(item, obj, name); method != nil {
 		return method, typ
@@ -260,7 +261,14 @@ func (r *reflectCache) get(obj any, name string) (any, reflect.Type) {
 }
 
 // set 设置对象的字段值，支持指针类型
-func (r *reflectCache) set(obj any, fieldName string, value any) error {
+func (r *reflectCache) set(obj any, fieldName string, value any) (err error) {
+	// 反射操作失败时会panic，转换为错误返回
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("cannot set field %s: %v", fieldName, rec)
+		}
+	}()
+
 	item := r.analyze(obj)
 	if item == nil {
 		return fmt.Errorf("failed to analyze object")
@@ -271,9 +279,9 @@ func (r *reflectCache) set(obj any, fieldName string, value any) error {
 		return fmt.Errorf("field %s not found", fieldName)
 	}
 
-	valueToSet := reflect.ValueOf(value)
-	if !valueToSet.Type().AssignableTo(field.typ) {
-		return fmt.Errorf("cannot assign value of type %s to field of type %s", valueToSet.Type(), field.typ)
+	valueToSet, err := toValue(value, field.typ)
+	if err != nil {
+		return fmt.Errorf("cannot assign to field %s: %v", fieldName, err)
 	}
 
 	v := reflect.ValueOf(obj)
@@ -308,7 +316,7 @@ func (r *reflectCache) set(obj any, fieldName string, value any) error {
 		}
 
 		// 类型转换处理
-		convertedValue, err := convertType(reflect.ValueOf(value), fieldValue.Type())
+		convertedValue, err := convertType(valueToSet, fieldValue.Type())
 		if err != nil {
 			return err
 		}
@@ -319,7 +327,7 @@ func (r *reflectCache) set(obj any, fieldName string, value any) error {
 	} else {
 		v = v.Elem()
 	}
-	fieldValue := v.FieldByIndex(field.index)
+	fieldValue := fieldByIndex(v, field.index)
 	if !fieldValue.CanSet() {
 		return fmt.Errorf("cannot set field %s: field is not settable", fieldName)
 	}
@@ -328,6 +336,138 @@ func (r *reflectCache) set(obj any, fieldName string, value any) error {
 	return nil
 }
 
+// 按索引路径获取字段，脚本中定义的结构体的未导出字段通过unsafe访问
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	field := v.FieldByIndex(index)
+	if field.CanInterface() {
+		return field
+	}
+	if !field.CanAddr() {
+		// 复制到可寻址的值中再访问
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		field = ptr.Elem().FieldByIndex(index)
+	}
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// toValue 将脚本中的值转换为指定类型的反射值
+// nil 转换为零值，数值类型之间可以互相转换，脚本中的 []any 和 map 会逐个元素转换，脚本函数转换为对应类型的函数
+func toValue(value any, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if fn, ok := value.(func(...any) (any, error)); ok && t.Kind() == reflect.Func {
+		return funcOf(fn, t), nil
+	}
+	switch {
+	case isNumberKind(v.Kind()) && isNumberKind(t.Kind()),
+		v.Kind() == reflect.String && t.Kind() == reflect.String,
+		v.Kind() == reflect.Bool && t.Kind() == reflect.Bool:
+		return v.Convert(t), nil
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && t.Kind() == reflect.Slice:
+		out := reflect.MakeSlice(t, v.Len(), v.Len())
+		for n := 0; n < v.Len(); n++ {
+			elem, err := toValue(v.Index(n).Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(n).Set(elem)
+		}
+		return out, nil
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && t.Kind() == reflect.Array && v.Len() == t.Len():
+		out := reflect.New(t).Elem()
+		for n := 0; n < v.Len(); n++ {
+			elem, err := toValue(v.Index(n).Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(n).Set(elem)
+		}
+		return out, nil
+	case v.Kind() == reflect.Map && t.Kind() == reflect.Map:
+		out := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := toValue(iter.Key().Interface(), t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			elem, err := toValue(iter.Value().Interface(), t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(key, elem)
+		}
+		return out, nil
+	}
+	return reflect.Value{}, fmt.Errorf("类型不匹配: 无法将 %s 转换为 %s", typeName(v.Type()), typeName(t))
+}
+
+// funcOf 将脚本函数包装为指定类型的函数，使脚本中的闭包可以作为回调传给宿主函数（如 sort.Slice）
+// 返回值转换为函数的结果类型；脚本函数执行出错时，如果最后一个结果是 error 则作为该结果返回，否则panic
+func funcOf(fn func(...any) (any, error), t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		in := make([]any, len(args))
+		for idx, arg := range args {
+			in[idx] = arg.Interface()
+		}
+		if t.IsVariadic() {
+			// 可变参数展开后传给脚本函数
+			last := args[len(args)-1]
+			in = in[:len(in)-1]
+			for n := 0; n < last.Len(); n++ {
+				in = append(in, last.Index(n).Interface())
+			}
+		}
+
+		out := make([]reflect.Value, t.NumOut())
+		for idx := range out {
+			out[idx] = reflect.Zero(t.Out(idx))
+		}
+		result, err := fn(in...)
+		if err != nil {
+			if len(out) > 0 && t.Out(len(out)-1) == errorType {
+				out[len(out)-1] = reflect.ValueOf(&err).Elem()
+				return out
+			}
+			panic(err)
+		}
+		if len(out) == 0 {
+			return out
+		}
+		values, ok := result.(tuple)
+		if !ok {
+			values = tuple{result}
+		}
+		if len(values) != len(out) {
+			panic(fmt.Errorf("回调函数的返回值数量不匹配: 期望 %d, 得到 %d", len(out), len(values)))
+		}
+		for idx, value := range values {
+			converted, err := toValue(value, t.Out(idx))
+			if err != nil {
+				panic(err)
+			}
+			out[idx] = converted
+		}
+		return out
+	})
+}
+
 // 新增类型转换函数
 func convertType(src reflect.Value, dstType reflect.Type) (reflect.Value, error) {
 	if src.Type().ConvertibleTo(dstType) {
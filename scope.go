@@ -1,12 +1,107 @@
 package goscript
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 type Scope struct {
 	sync.Map
 	parent *Scope
+	// 编译时确定了变量布局的作用域（BackendClosure），布局中的变量保存在 slots 中，其它的键仍然保存在 sync.Map 中
+	layout *scopeLayout
+	slots  []any
+	// 是否在 sync.Map 中保存过布局之外的变量名
+	extra int32
 }
 
 type SharedScope struct {
 	sync.Map
 }
+
+// scopeLayout 编译时确定的作用域中声明的变量及其在 slots 中的位置
+type scopeLayout struct {
+	index map[string]int
+	// 声明为常量的名字
+	consts map[string]bool
+}
+
+func newLayout() *scopeLayout {
+	return &scopeLayout{index: make(map[string]int), consts: make(map[string]bool)}
+}
+
+// 变量在布局中的位置，不在布局中时返回 -1
+func (l *scopeLayout) indexOf(name string) int {
+	if idx, ok := l.index[name]; ok {
+		return idx
+	}
+	return -1
+}
+
+func (l *scopeLayout) add(name string) {
+	if _, ok := l.index[name]; !ok {
+		l.index[name] = len(l.index)
+	}
+}
+
+// slotNil 值为 nil 的变量在 slots 中的表示，slots 中的 nil 表示变量还没有定义
+type slotNil struct{}
+
+// 创建作用域，layout 不为 nil 时布局中的变量保存在 slots 中
+func newScope(parent *Scope, layout *scopeLayout) *Scope {
+	s := &Scope{parent: parent, layout: layout}
+	if layout != nil {
+		s.slots = make([]any, len(layout.index))
+	}
+	return s
+}
+
+// Load 读取作用域中保存的值
+func (s *Scope) Load(key any) (any, bool) {
+	if s.layout != nil {
+		if name, ok := key.(string); ok {
+			if idx, ok := s.layout.index[name]; ok {
+				return s.slot(idx)
+			}
+		}
+	}
+	return s.Map.Load(key)
+}
+
+// Store 在作用域中保存值
+func (s *Scope) Store(key, value any) {
+	if s.layout != nil {
+		if name, ok := key.(string); ok {
+			if idx, ok := s.layout.index[name]; ok {
+				s.setSlot(idx, value)
+				return
+			}
+			atomic.StoreInt32(&s.extra, 1)
+		}
+	}
+	s.Map.Store(key, value)
+}
+
+// 读取 slots 中的变量，ok 为 false 表示变量还没有定义
+func (s *Scope) slot(idx int) (any, bool) {
+	value := s.slots[idx]
+	if value == nil {
+		return nil, false
+	}
+	if _, ok := value.(slotNil); ok {
+		return nil, true
+	}
+	return value, true
+}
+
+func (s *Scope) setSlot(idx int, value any) {
+	if value == nil {
+		value = slotNil{}
+	}
+	s.slots[idx] = value
+}
+
+// 是否在 sync.Map 中保存过布局之外的变量名
+func (s *Scope) hasExtra() bool {
+	return atomic.LoadInt32(&s.extra) != 0
+}
@@ -0,0 +1,129 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+)
+
+// 处理切片表达式 s[low:high] 和 s[low:high:max]，支持 []any、字符串以及宿主的切片和数组
+// 数组值在脚本中不可寻址，对数组切片得到的是数组副本的切片；对数组指针切片则引用原数组
+func (i *Interpreter) evalSliceExpr(expr *ast.SliceExpr) (any, error) {
+	container, err := i.eval(expr.X)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes [3]int
+	for n, e := range []ast.Expr{expr.Low, expr.High, expr.Max} {
+		indexes[n] = -1
+		if e == nil {
+			continue
+		}
+		value, err := i.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		index, ok := toInt(value)
+		if !ok {
+			return nil, fmt.Errorf("切片索引必须是整数，得到: %T", value)
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("切片索引不能为负数: %d", index)
+		}
+		indexes[n] = index
+	}
+	low, high, max := indexes[0], indexes[1], indexes[2]
+	if low < 0 {
+		low = 0
+	}
+
+	switch c := container.(type) {
+	case string:
+		if expr.Slice3 {
+			return nil, fmt.Errorf("字符串不支持三索引切片")
+		}
+		if high < 0 {
+			high = len(c)
+		}
+		if err := checkSliceBounds(low, high, -1, len(c)); err != nil {
+			return nil, err
+		}
+		return c[low:high], nil
+	case []any:
+		if high < 0 {
+			high = len(c)
+		}
+		if err := checkSliceBounds(low, high, max, cap(c)); err != nil {
+			return nil, err
+		}
+		if expr.Slice3 {
+			return c[low:high:max], nil
+		}
+		return c[low:high], nil
+	}
+
+	v := reflect.ValueOf(container)
+	switch {
+	case v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Array:
+		v = v.Elem()
+	case v.Kind() == reflect.Array:
+		// 数组值不可寻址，复制一份再切片
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr.Elem()
+	case v.Kind() == reflect.String:
+		if expr.Slice3 {
+			return nil, fmt.Errorf("字符串不支持三索引切片")
+		}
+	case v.Kind() != reflect.Slice:
+		return nil, fmt.Errorf("不支持的切片操作: %T", container)
+	}
+
+	if high < 0 {
+		high = v.Len()
+	}
+	capacity := v.Len()
+	if v.Kind() != reflect.String {
+		capacity = v.Cap()
+	}
+	if err := checkSliceBounds(low, high, max, capacity); err != nil {
+		return nil, err
+	}
+	if expr.Slice3 {
+		return v.Slice3(low, high, max).Interface(), nil
+	}
+	return v.Slice(low, high).Interface(), nil
+}
+
+// 按照go的规则检查切片的边界 0 <= low <= high <= max <= cap，max 为 -1 表示没有指定
+func checkSliceBounds(low, high, max, capacity int) error {
+	if max >= 0 {
+		if max > capacity {
+			return fmt.Errorf("切片越界: [::%d] 容量为 %d", max, capacity)
+		}
+		capacity = max
+	}
+	if high > capacity {
+		return fmt.Errorf("切片越界: [:%d] 容量为 %d", high, capacity)
+	}
+	if low > high {
+		return fmt.Errorf("切片越界: [%d:%d]", low, high)
+	}
+	return nil
+}
+
+// 将整数类型的值转换为 int
+func toInt(value any) (int, bool) {
+	if n, ok := value.(int); ok {
+		return n, true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int(v.Uint()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,112 @@
+package goscript
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSliceExpr(t *testing.T) {
+	bindings := map[string]any{
+		"names": []string{"a", "b", "c", "d"},
+		"arr":   [3]int{1, 2, 3},
+	}
+
+	// []any 切片
+	t.Run("Any Slice", func(t *testing.T) {
+		result, err := newTestInterpreter(bindings).Interpret(`
+		items := []any{1, 2, 3, 4, 5}
+		[]any{items[1:3], items[:2], items[3:], items[:], len(items[1:2:4])}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		expected := []any{[]any{2, 3}, []any{1, 2}, []any{4, 5}, []any{1, 2, 3, 4, 5}, 1}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 三索引切片限制容量
+	t.Run("Three Index", func(t *testing.T) {
+		result, err := newTestInterpreter(bindings).Interpret(`
+		items := []any{1, 2, 3, 4, 5}
+		s := items[1:2:3]
+		s[0:2]
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(result, []any{2, 3}) {
+			t.Errorf("Expected [2 3], got %v", result)
+		}
+		_, err = newTestInterpreter(bindings).Interpret(`
+		items := []any{1, 2, 3, 4, 5}
+		s := items[1:2:3]
+		s[0:3]
+		`)
+		if err == nil {
+			t.Errorf("Expected error")
+		}
+	})
+
+	// 字符串切片
+	t.Run("String", func(t *testing.T) {
+		result, err := newTestInterpreter(bindings).Interpret(`
+		s := "hello world"
+		s[:5] + "," + s[6:]
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if result != "hello,world" {
+			t.Errorf("Expected hello,world, got %v", result)
+		}
+	})
+
+	// 宿主的切片和数组
+	t.Run("Host", func(t *testing.T) {
+		result, err := newTestInterpreter(bindings).Interpret(`
+		[]any{names[1:3], arr[1:]}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		expected := []any{[]string{"b", "c"}, []int{2, 3}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 越界返回错误而不是panic
+	t.Run("Out Of Range", func(t *testing.T) {
+		for _, code := range []string{
+			`names[2:5]`,
+			`names[3:1]`,
+			`"abc"[:4]`,
+			`[]any{1}[2:]`,
+			`names[0:1:5]`,
+			`"abc"[0:1:2]`,
+		} {
+			if _, err := newTestInterpreter(bindings).Interpret(code); err == nil {
+				t.Errorf("Expected error for %s", code)
+			}
+		}
+
+		// 切片字面量的容量与长度相同
+		result := runScript(t, newTestInterpreter(bindings), `
+		xs := []int{1, 2, 3}
+		cap(xs)
+		`)
+		if result != 3 {
+			t.Errorf("Expected 3, got %v", result)
+		}
+		_, err := newTestInterpreter(bindings).Interpret(`
+		xs := []int{1, 2, 3}
+		xs[0:len(xs)+1]
+		`)
+		if err == nil || !strings.Contains(err.Error(), "容量为 3") {
+			t.Errorf("Expected out of range error with capacity 3, got %v", err)
+		}
+	})
+}
@@ -3,7 +3,9 @@ package goscript
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // 注册标准库包
@@ -58,6 +60,29 @@ func (i *Interpreter) libs() {
 		"TrimSuffix":     strings.TrimSuffix,
 	})
 
+	// strconv 包
+	i.Set("strconv", map[string]any{
+		"Atoi":        strconv.Atoi,
+		"Itoa":        strconv.Itoa,
+		"ParseBool":   strconv.ParseBool,
+		"ParseFloat":  strconv.ParseFloat,
+		"ParseInt":    strconv.ParseInt,
+		"ParseUint":   strconv.ParseUint,
+		"FormatBool":  strconv.FormatBool,
+		"FormatFloat": strconv.FormatFloat,
+		"FormatInt":   strconv.FormatInt,
+		"Quote":       strconv.Quote,
+		"Unquote":     strconv.Unquote,
+	})
+
+	// sync 包，用于等待和同步脚本中的goroutine
+	i.Set("sync", map[string]any{
+		"WaitGroup": reflect.TypeOf(sync.WaitGroup{}),
+		"Mutex":     reflect.TypeOf(sync.Mutex{}),
+		"RWMutex":   reflect.TypeOf(sync.RWMutex{}),
+		"Once":      reflect.TypeOf(sync.Once{}),
+	})
+
 	// fmt 包
 	i.Set("fmt", map[string]any{
 		"Println": fmt.Println,
@@ -66,6 +91,9 @@ func (i *Interpreter) libs() {
 	})
 
 	i.Set("len", func(v any) int {
+		if v == nil {
+			return 0
+		}
 		return reflect.ValueOf(v).Len()
 	})
 
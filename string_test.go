@@ -2,6 +2,7 @@ package goscript
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -21,6 +22,57 @@ func TestString(t *testing.T) {
 	fmt.Println(123)
 }
 
+func TestStringLiterals(t *testing.T) {
+	// 双引号字符串按go的规则处理转义，反引号字符串保持原样
+	t.Run("Interpreted And Raw", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `[]any{"a\nb", "\u00e9\t\x41", "say \"hi\"", `+"`a\\nb`"+`}`)
+		expected := []any{"a\nb", "é\tA", `say "hi"`, `a\nb`}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	})
+
+	// 单引号中是一个字符或转义序列时是rune字面量
+	t.Run("Rune", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `[]any{'x', '\n', '\'', 'é', '\u4e2d', 'x' + 1}`)
+		expected := []any{'x', '\n', '\'', 'é', '中', int32('y')}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 字符串与rune相加时拼接字符，rune与字符串比较时不相等
+	t.Run("Rune And String", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `
+		s := "a"
+		word := ""
+		for _, r := range "hi" {
+			word = word + r
+		}
+		[]any{"x" + 'a', 'b' + "y", word, s == 'a', s == "a", "n" + 97}
+		`)
+		expected := []any{"xa", "by", "hi", false, true, "n97"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+		if _, err := NewInterpreter().Interpret(`
+		m := map[string]any{"k": 1}
+		m['k']
+		`); err == nil {
+			t.Errorf("Expected error for rune map key")
+		}
+	})
+
+	// 其它单引号内容是字符串，支持转义
+	t.Run("Single Quote String", func(t *testing.T) {
+		result := runScript(t, NewInterpreter(), `[]any{'hello', '', 'it\'s', 'He said "hi"', 'a\tb'}`)
+		expected := []any{"hello", "", "it's", `He said "hi"`, "a\tb"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	})
+}
+
 // // 测试解释器对单引号字符串的处理
 // func TestSingleQuoteString(t *testing.T) {
 // 	interp := NewInterpreter()
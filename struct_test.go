@@ -0,0 +1,148 @@
+package goscript
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStructType(t *testing.T) {
+	interp := NewInterpreter()
+	interp.Set("toJSON", func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err.Error()
+		}
+		return string(b)
+	})
+
+	// 按字段名和按位置初始化，字段读写
+	t.Run("Literal And Fields", func(t *testing.T) {
+		result, err := interp.Interpret(`
+		type Order struct {
+			ID    int      ` + "`json:\"id\"`" + `
+			Items []string
+			note  string
+		}
+		a := Order{ID: 1, Items: []string{"x", "y"}}
+		b := Order{2, []string{"z"}, "secret"}
+		a.ID = a.ID + 10
+		return a.ID, b.Items[0], b.note, toJSON(a)
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(result, []any{11, "z", "secret", `{"id":11,"Items":["x","y"]}`}) {
+			t.Errorf("Unexpected result: %v", result)
+		}
+	})
+
+	// 零值、值语义和嵌套字段赋值
+	t.Run("Zero Value And Copy", func(t *testing.T) {
+		result, err := interp.Interpret(`
+		type Point struct {
+			X int
+			Y int
+		}
+		type Line struct {
+			From Point
+			To   Point
+		}
+		var l Line
+		l.To.X = 3
+		p := l.To
+		p.X = 100
+		return l.From.X, l.To.X, p.X
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(result, []any{0, 3, 100}) {
+			t.Errorf("Expected [0 3 100], got %v", result)
+		}
+	})
+
+	// 省略类型的元素、嵌入字段和引用自身的字段
+	t.Run("Elided Embedded Recursive", func(t *testing.T) {
+		result, err := interp.Interpret(`
+		type Base struct {
+			Name string
+		}
+		type Node struct {
+			Base
+			Next *Node
+		}
+		nodes := []Node{{Base: Base{Name: "a"}}, {Base{"b"}, nil}}
+		m := map[string]Base{"k": {Name: "c"}}
+		n := nodes[1]
+		return nodes[0].Name, n.Name, m["k"].Name, n.Next == nil
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if !reflect.DeepEqual(result, []any{"a", "b", "c", true}) {
+			t.Errorf("Unexpected result: %v", result)
+		}
+	})
+
+	// 结构相同但名称不同的类型是不同的类型
+	t.Run("Distinct Types", func(t *testing.T) {
+		result, err := interp.Interpret(`
+		type A struct { V int }
+		type B struct { V int }
+		return A{1}, B{1}
+		`)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		values := result.([]any)
+		if reflect.TypeOf(values[0]) == reflect.TypeOf(values[1]) {
+			t.Errorf("Expected distinct types")
+		}
+		if typeName(reflect.TypeOf(values[0])) != "A" {
+			t.Errorf("Expected type name A, got %s", typeName(reflect.TypeOf(values[0])))
+		}
+	})
+
+	// 错误的字段
+	t.Run("Errors", func(t *testing.T) {
+		cases := []string{
+			`type P struct { X int }
+			p := P{Z: 1}`,
+			`type P struct { X int }
+			p := P{1, 2}`,
+			`type P struct { X int }
+			p := P{}
+			p.X = "str"`,
+		}
+		for _, code := range cases {
+			if _, err := interp.Interpret(code); err == nil {
+				t.Errorf("Expected error for %q", code)
+			}
+		}
+	})
+
+	// 不能嵌入带有方法的宿主类型，具名字段可以正常使用
+	t.Run("Embedded Host Type", func(t *testing.T) {
+		for _, code := range []string{
+			`type S struct { sync.Mutex }`,
+			`type S struct { Name string; *strings.Builder }`,
+		} {
+			_, err := interp.Interpret(code)
+			if err == nil || !strings.Contains(err.Error(), "请使用具名字段") {
+				t.Errorf("Expected embed error for %q, got %v", code, err)
+			}
+		}
+		result, err := interp.Interpret(`
+		type S struct { mu sync.Mutex; n int }
+		s := S{}
+		s.mu.Lock()
+		s.n++
+		s.mu.Unlock()
+		s.n`)
+		if err != nil || result != 1 {
+			t.Errorf("Expected 1, got %v, %v", result, err)
+		}
+	})
+}
@@ -0,0 +1,344 @@
+package goscript
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 脚本中定义的结构体的未导出字段所属的包路径
+const scriptPkgPath = "goscript/script"
+
+// 记录脚本类型名称的结构体tag
+// 类型名写在第一个字段的tag中，这样结构相同但名称不同的脚本类型是不同的反射类型
+const scriptTypeTag = "goscript"
+
+// interface{} 的反射类型
+var anyType = reflect.TypeOf((*any)(nil)).Elem()
+
+// structOf 根据脚本中的结构体定义创建反射类型，name 为空时表示匿名结构体
+// 引用自身的字段（如 Next *Node）无法用反射表示，这类字段的类型退化为 any
+func (i *Interpreter) structOf(name string, st *ast.StructType) (t reflect.Type, err error) {
+	var fields []reflect.StructField
+	for _, field := range st.Fields.List {
+		typ := anyType
+		if name == "" || !refersTo(field.Type, name) {
+			if typ, err = i.resolveType(field.Type); err != nil {
+				return nil, err
+			}
+		}
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			value, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, fmt.Errorf("无效的结构体tag: %s", field.Tag.Value)
+			}
+			tag = reflect.StructTag(value)
+		}
+		if len(field.Names) == 0 {
+			// 嵌入字段，字段名为类型名
+			// reflect.StructOf 无法正确生成嵌入类型的方法，所以不能嵌入带有方法的宿主类型（如 sync.Mutex），需要使用具名字段
+			if hasMethods(typ) {
+				return nil, fmt.Errorf("不能嵌入带有方法的宿主类型 %s，请使用具名字段", typeName(typ))
+			}
+			fields = append(fields, newStructField(embeddedName(field.Type), typ, tag, true))
+			continue
+		}
+		for _, fieldName := range field.Names {
+			fields = append(fields, newStructField(fieldName.Name, typ, tag, false))
+		}
+	}
+
+	nameTag := fmt.Sprintf(`%s:%q`, scriptTypeTag, name)
+	if len(fields) == 0 {
+		// 空结构体使用一个零大小的字段记录类型名
+		fields = append(fields, newStructField("_", reflect.TypeOf(struct{}{}), "", false))
+	}
+	fields[0].Tag = reflect.StructTag(strings.TrimSpace(nameTag + " " + string(fields[0].Tag)))
+
+	// StructOf 对不支持的定义会panic
+	defer func() {
+		if r := recover(); r != nil {
+			t, err = nil, fmt.Errorf("无法定义结构体 %s: %v", name, r)
+		}
+	}()
+	return reflect.StructOf(fields), nil
+}
+
+// 类型表达式中是否引用了指定的类型名
+func refersTo(expr ast.Expr, name string) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func newStructField(name string, typ reflect.Type, tag reflect.StructTag, anonymous bool) reflect.StructField {
+	field := reflect.StructField{Name: name, Type: typ, Tag: tag, Anonymous: anonymous}
+	if !ast.IsExported(name) {
+		field.PkgPath = scriptPkgPath
+	}
+	return field
+}
+
+// 类型或其指针类型是否有方法，脚本中定义的类型的方法不在反射的方法集中
+func hasMethods(t reflect.Type) bool {
+	if t.NumMethod() > 0 {
+		return true
+	}
+	return t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && reflect.PtrTo(t).NumMethod() > 0
+}
+
+// 嵌入字段的字段名
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// isScriptType 判断是否是脚本中定义的结构体类型
+func isScriptType(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.NumField() == 0 {
+		return false
+	}
+	_, ok := t.Field(0).Tag.Lookup(scriptTypeTag)
+	return ok
+}
+
+// typeName 返回类型在脚本中的名称，用于错误信息等
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return "nil"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + typeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + typeName(t.Elem())
+	case reflect.Struct:
+		if isScriptType(t) {
+			if name := t.Field(0).Tag.Get(scriptTypeTag); name != "" {
+				return name
+			}
+		}
+	}
+	return t.String()
+}
+
+// 处理类型声明，脚本中的类型以反射类型的形式保存在作用域中
+// 非结构体的命名类型（如 type Celsius float64）等同于其底层类型的别名，不能声明方法
+func (i *Interpreter) evalTypeSpec(spec *ast.TypeSpec) error {
+	var typ reflect.Type
+	var err error
+	if it, ok := spec.Type.(*ast.InterfaceType); ok && isConstraint(it) {
+		// 包含类型集合的接口只能用作泛型的约束
+		i.scope.Store(spec.Name.Name, &typeConstraint{name: spec.Name.Name, expr: it})
+		return nil
+	}
+	if st, ok := spec.Type.(*ast.StructType); ok && !spec.Assign.IsValid() {
+		typ, err = i.structOf(spec.Name.Name, st)
+	} else {
+		typ, err = i.resolveType(spec.Type)
+	}
+	if err != nil {
+		return err
+	}
+	i.scope.Store(spec.Name.Name, typ)
+	return nil
+}
+
+// 创建结构体的值，支持按字段名和按位置初始化
+func (i *Interpreter) newStruct(typ reflect.Type, lit *ast.CompositeLit) (any, error) {
+	ptr := reflect.New(typ)
+	keyed := len(lit.Elts) > 0
+	if keyed {
+		_, keyed = lit.Elts[0].(*ast.KeyValueExpr)
+	}
+
+	if !keyed && len(lit.Elts) > 0 && len(lit.Elts) != typ.NumField() {
+		return nil, fmt.Errorf("%s 的字面量字段数量不匹配: 需要 %d 个, 得到 %d 个", typeName(typ), typ.NumField(), len(lit.Elts))
+	}
+	for idx, elt := range lit.Elts {
+		var name string
+		valueExpr := elt
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			key, ok := kv.Key.(*ast.Ident)
+			if !ok || !keyed {
+				return nil, fmt.Errorf("%s 的字面量不能混用字段名和位置", typeName(typ))
+			}
+			name, valueExpr = key.Name, kv.Value
+		} else if keyed {
+			return nil, fmt.Errorf("%s 的字面量不能混用字段名和位置", typeName(typ))
+		} else {
+			name = typ.Field(idx).Name
+		}
+
+		field, ok := typ.FieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("%s 没有字段 %s", typeName(typ), name)
+		}
+		value, err := i.evalElement(valueExpr, field.Type)
+		if err != nil {
+			return nil, err
+		}
+		if err := globalReflectCache.set(ptr.Interface(), name, value); err != nil {
+			return nil, err
+		}
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// 计算复合字面量中的元素，元素中省略类型的复合字面量（如 []Order{{ID: 1}}）使用容器的元素类型
+func (i *Interpreter) evalElement(expr ast.Expr, elemType reflect.Type) (any, error) {
+	if lit, ok := expr.(*ast.CompositeLit); ok && lit.Type == nil && elemType != nil {
+		if elemType.Kind() == reflect.Ptr {
+			// []*Order{{ID: 1}} 等同于 []*Order{&Order{ID: 1}}
+			value, err := i.evalCompositeLitOf(lit, elemType.Elem())
+			if err != nil {
+				return nil, err
+			}
+			ptr := reflect.New(elemType.Elem())
+			ptr.Elem().Set(reflect.ValueOf(value))
+			return ptr.Interface(), nil
+		}
+		return i.evalCompositeLitOf(lit, elemType)
+	}
+	return i.eval(expr)
+}
+
+// methodKey 方法在作用域中保存时使用的键，不会和变量名冲突
+type methodKey struct {
+	typ  reflect.Type
+	name string
+}
+
+// scriptMethod 脚本类型上声明的方法，fn 的第一个参数是接收器
+type scriptMethod struct {
+	// 指针接收器
+	pointer bool
+	fn      func(args ...any) (any, error)
+}
+
+// 处理方法声明 func (o *Order) Total() int {...}，方法保存在当前作用域中，方法体在 scope 中执行
+func (i *Interpreter) declareMethod(decl *ast.FuncDecl, scope *Scope) error {
+	recv := decl.Recv.List[0]
+	typeExpr := recv.Type
+	star, pointer := typeExpr.(*ast.StarExpr)
+	if pointer {
+		typeExpr = star.X
+	}
+	typ, err := i.resolveType(typeExpr)
+	if err != nil {
+		return err
+	}
+	if !isScriptType(typ) {
+		if ident, ok := typeExpr.(*ast.Ident); ok && basicTypes[ident.Name] == nil {
+			// 非结构体的命名类型只是底层类型的别名，没有自己的方法集
+			return fmt.Errorf("无法为 %s 声明方法 %s: %s 的底层类型是 %s，只能为脚本中定义的结构体类型声明方法", ident.Name, decl.Name.Name, ident.Name, typeName(typ))
+		}
+		return fmt.Errorf("无法为 %s 声明方法 %s: 只能为脚本中定义的结构体类型声明方法", typeName(typ), decl.Name.Name)
+	}
+
+	// 接收器作为方法的第一个参数
+	if len(recv.Names) == 0 {
+		recv = &ast.Field{Names: []*ast.Ident{ast.NewIdent("_")}, Type: recv.Type}
+	}
+	params := append([]*ast.Field{recv}, decl.Type.Params.List...)
+	fnType := &ast.FuncType{Params: &ast.FieldList{List: params}, Results: decl.Type.Results}
+	i.scope.Store(methodKey{typ, decl.Name.Name}, &scriptMethod{
+		pointer: pointer,
+		fn:      i.withScope(scope).newFunction(fnType, decl.Body),
+	})
+	return nil
+}
+
+// 在作用域链中查找类型上声明的方法
+func (i *Interpreter) lookupMethod(typ reflect.Type, name string) *scriptMethod {
+	key := methodKey{typ, name}
+	for currentScope := i.scope; currentScope != nil; currentScope = currentScope.parent {
+		if m, ok := currentScope.Load(key); ok {
+			return m.(*scriptMethod)
+		}
+	}
+	return nil
+}
+
+// 在可寻址的结构体或结构体指针上查找方法，包括通过嵌入字段提升的方法，返回方法和对应的接收器
+func (i *Interpreter) findMethod(v reflect.Value, name string) (*scriptMethod, reflect.Value) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, reflect.Value{}
+	}
+	if m := i.lookupMethod(v.Type(), name); m != nil {
+		if m.pointer {
+			return m, v.Addr()
+		}
+		return m, v
+	}
+	// 通过嵌入字段提升的方法
+	for idx := 0; idx < v.NumField(); idx++ {
+		if !v.Type().Field(idx).Anonymous {
+			continue
+		}
+		if m, recv := i.findMethod(fieldByIndex(v, []int{idx}), name); m != nil {
+			return m, recv
+		}
+	}
+	return nil, reflect.Value{}
+}
+
+// methodValue 获取绑定了接收器的方法值，如 f := o.Total
+// 结构体值不可寻址，方法在它的副本上查找；此时如果方法是指针接收器，writeBack 为 true，
+// 调用者需要在调用后将副本写回原来的位置
+func (i *Interpreter) methodValue(container any, name string) (fn func(args ...any) (any, error), copied reflect.Value, writeBack bool) {
+	v := reflect.ValueOf(container)
+	if !v.IsValid() {
+		return nil, reflect.Value{}, false
+	}
+	if v.Kind() == reflect.Struct {
+		if !isScriptType(v.Type()) {
+			return nil, reflect.Value{}, false
+		}
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		copied = ptr.Elem()
+		v = ptr
+	}
+	m, recv := i.findMethod(v, name)
+	if m == nil {
+		return nil, reflect.Value{}, false
+	}
+	receiver := recv.Interface()
+	return func(args ...any) (any, error) {
+		caller, args := callerOf(args)
+		return m.fn(withCaller(caller, append([]any{receiver}, args...))...)
+	}, copied, m.pointer && copied.IsValid()
+}
+
+// 表达式是否可以作为赋值的目标
+func isAddressable(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident, *ast.IndexExpr, *ast.SelectorExpr, *ast.StarExpr:
+		return true
+	case *ast.ParenExpr:
+		return isAddressable(e.X)
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package goscript
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVariadic(t *testing.T) {
+	bindings := map[string]any{
+		"join": func(sep string, parts ...string) string {
+			return strings.Join(parts, sep)
+		},
+		"words": []string{"b", "c"},
+	}
+	// 同一类型的多个参数共用一个类型
+	t.Run("Grouped Params", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		func sum(a, b int, c int) int {
+			return a + b + c
+		}
+		add := func(x, y int) int {
+			return x + y
+		}
+		[]any{sum(1, 2, 3), add(4, 5)}
+		`)
+		if !reflect.DeepEqual(result, []any{6, 9}) {
+			t.Errorf("Expected [6 9], got %v", result)
+		}
+	})
+
+	// 可变参数收集为切片，没有可变参数时为nil
+	t.Run("Variadic Params", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		func count(prefix string, nums ...int) any {
+			total := 0
+			for _, n := range nums {
+				total += n
+			}
+			return []any{prefix, len(nums), total, nums == nil}
+		}
+		[]any{count("a", 1, 2, 3), count("b")}
+		`)
+		expected := []any{[]any{"a", 3, 6, false}, []any{"b", 0, 0, true}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 展开切片调用脚本函数和宿主函数
+	t.Run("Spread", func(t *testing.T) {
+		result := runScript(t, newTestInterpreter(bindings), `
+		func count(nums ...int) int {
+			return len(nums)
+		}
+		xs := []any{1, 2, 3}
+		rest := []any{"x", "y"}
+		[]any{count(xs...), count(), join("-", "a", "b"), join("-", rest...), join("+", words...)}
+		`)
+		expected := []any{3, 0, "a-b", "x-y", "b+c"}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	// 参数数量错误
+	t.Run("Arity Errors", func(t *testing.T) {
+		for _, code := range []string{
+			`f := func(a, b int) {}
+			f(1)`,
+			`f := func(a string, rest ...int) {}
+			f()`,
+			`strings.ToUpper([]any{"a"}...)`,
+		} {
+			if _, err := newTestInterpreter(bindings).Interpret(code); err == nil {
+				t.Errorf("Expected error for %s", code)
+			}
+		}
+	})
+}
@@ -0,0 +1,313 @@
+package goscript
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+// 在虚拟机中执行一段字节码，结果与遍历AST执行这条语句相同：
+// 语句产生的控制流信号（return、跳出外层的 break 等）作为结果返回
+// 执行中发生的go运行时panic与 evalStmtList 一样转换为脚本的panic，位置是指令所在的语句
+func (c *chunk) run(i *Interpreter) (result any, err error) {
+	var buf [16]any
+	stack := buf[:0]
+	// 进入作用域前的解释器，退出作用域时恢复
+	var scopes []*Interpreter
+
+	pc := 0
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, i.runtimePanic(r, c.stmts[pc])
+		}
+	}()
+	for ; pc < len(c.code); pc++ {
+		in := c.code[pc]
+		switch in.op {
+		case opConst:
+			stack = append(stack, c.consts[in.a])
+
+		case opLoad:
+			value, err := i.lookup(c.idents[in.a])
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, value)
+
+		case opGlobal:
+			stack = append(stack, i.global)
+
+		case opPop:
+			stack = stack[:len(stack)-1]
+
+		case opSingle:
+			if _, err := expandValues(stack[len(stack)-1:], 1); err != nil {
+				return nil, err
+			}
+
+		case opBinary:
+			n := len(stack)
+			value, err := c.binops[in.a].apply(i, stack[n-2], stack[n-1])
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack[:n-2], value)
+
+		case opAssign:
+			target := c.assigns[in.a]
+			n := len(stack) - len(target.lhs)
+			values, err := expandValues(stack[n:], len(target.lhs))
+			if err != nil {
+				return nil, err
+			}
+			for idx, lhs := range target.lhs {
+				if err := i.assign(lhs, values[idx], target.define); err != nil {
+					return nil, err
+				}
+			}
+			stack = stack[:n]
+
+		case opCompound:
+			// 与 compoundAssign 相同，map中不存在的键在与数值运算时视为零
+			n := len(stack)
+			y, x := stack[n-2], stack[n-1]
+			stack = stack[:n-2]
+			if x == nil && isNumber(y) {
+				x = reflect.Zero(reflect.TypeOf(y)).Interface()
+			}
+			value, err := c.binops[in.a].apply(i, x, y)
+			if err != nil {
+				return nil, err
+			}
+			if err := i.assign(c.assigns[in.b].lhs[0], value, false); err != nil {
+				return nil, err
+			}
+
+		case opUpdate:
+			n := len(stack)
+			y := stack[n-1]
+			stack = stack[:n-1]
+			err := i.update(c.assigns[in.b].lhs[0], func(x any) (any, error) {
+				if x == nil && isNumber(y) {
+					x = reflect.Zero(reflect.TypeOf(y)).Interface()
+				}
+				return c.binops[in.a].apply(i, x, y)
+			})
+			if err != nil {
+				return nil, err
+			}
+
+		case opSelect:
+			top := len(stack) - 1
+			value, err := i.selectValue(c.nodes[in.a].(*ast.SelectorExpr), stack[top])
+			if err != nil {
+				return nil, err
+			}
+			stack[top] = value
+
+		case opIndex:
+			n := len(stack)
+			var value any
+			var err error
+			if g, ok := stack[n-2].(*genericFunc); ok {
+				// 泛型函数的实例化 f[T]
+				value, err = i.instantiate(g, []ast.Expr{c.nodes[in.a].(*ast.IndexExpr).Index})
+			} else {
+				value, err = indexValue(stack[n-2], stack[n-1])
+			}
+			if err != nil {
+				return nil, i.atPos(err, c.nodes[in.a].Pos())
+			}
+			stack = append(stack[:n-2], value)
+
+		case opUnary:
+			top := len(stack) - 1
+			value, err := unaryOp(token.Token(in.a), stack[top])
+			if err != nil {
+				return nil, err
+			}
+			stack[top] = value
+
+		case opAddr:
+			value, err := i.evalAddressOf(c.nodes[in.a].(ast.Expr))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, value)
+
+		case opRecv:
+			top := len(stack) - 1
+			value, _, err := i.recv(c.nodes[in.a].(*ast.UnaryExpr), stack[top])
+			if err != nil {
+				return nil, err
+			}
+			stack[top] = value
+
+		case opConvert:
+			top := len(stack) - 1
+			if _, ok := stack[top].(reflect.Type); ok {
+				// 脚本中定义的类型的转换
+				value, err := i.walk(c.calls[in.a])
+				if err != nil {
+					return nil, err
+				}
+				stack[top] = value
+				pc = in.b - 1
+			}
+
+		case opMethod:
+			call := c.calls[in.a]
+			var value any
+			var err error
+			done := true
+			if typ, ok := i.conversionType(call.Fun); ok {
+				// 包中的类型的转换，如 time.Duration(n)
+				value, err = i.evalConversion(call, typ)
+			} else {
+				value, done, err = i.methodCallee(call, call.Fun.(*ast.SelectorExpr))
+			}
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, value)
+			if done {
+				pc = in.b - 1
+			}
+
+		case opCall:
+			// 与 evalArgs 相同，唯一的参数是多返回值时展开为多个参数
+			n := len(stack) - in.b
+			args := make([]any, in.b)
+			copy(args, stack[n:])
+			if len(args) == 1 {
+				if t, ok := args[0].(tuple); ok {
+					args = t
+				}
+			}
+			fn := stack[n-1]
+			stack = stack[:n-1]
+			value, err := i.callFunction(c.calls[in.a], fn, args)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, value)
+
+		case opEval:
+			value, err := i.walk(c.nodes[in.a])
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, value)
+
+		case opExec:
+			result, err := i.walk(c.nodes[in.a])
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := result.(controlFlow); !ok {
+				continue
+			}
+			if loop, target := branchTarget(c.loops[in.b], result); loop != nil {
+				i, scopes = restore(i, scopes, loop.depth)
+				pc = target - 1
+				continue
+			}
+			return result, nil
+
+		case opRange:
+			r := c.ranges[in.a]
+			top := len(stack) - 1
+			signal, err := i.rangeValue(r.stmt, stack[top], r.label)
+			if err != nil {
+				return nil, err
+			}
+			stack = stack[:top]
+			if signal == nil {
+				continue
+			}
+			if loop, target := branchTarget(c.loops[in.b], signal); loop != nil {
+				i, scopes = restore(i, scopes, loop.depth)
+				pc = target - 1
+				continue
+			}
+			return signal, nil
+
+		case opBranch:
+			return i.evalBranchStmt(c.nodes[in.a].(*ast.BranchStmt))
+
+		case opJump:
+			i, scopes = restore(i, scopes, in.b)
+			pc = in.a - 1
+
+		case opJumpFalse:
+			cond := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !toBool(cond) {
+				pc = in.a - 1
+			}
+
+		case opJumpTrue:
+			cond := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if toBool(cond) {
+				pc = in.a - 1
+			}
+
+		case opEnter:
+			scopes = append(scopes, i)
+			i = i.withScope(&Scope{parent: i.scope})
+
+		case opLeave:
+			i, scopes = restore(i, scopes, len(scopes)-1)
+
+		case opNextIter:
+			// 下一次迭代使用新的变量，初始值为本次迭代结束时的值
+			next := &Scope{parent: i.scope.parent}
+			for _, name := range c.names[in.a] {
+				value, _ := i.scope.Load(name)
+				next.Store(name, unbox(value))
+			}
+			i = i.withScope(next)
+
+		case opReturn:
+			values := make(returnValues, in.a)
+			copy(values, stack[len(stack)-in.a:])
+			return values, nil
+		}
+	}
+	return nil, nil
+}
+
+// 遍历AST执行的语句产生的 break 和 continue 跳转到的外层循环和位置，没有标签时是最内层的循环
+// 目标不是 loops 中的循环时返回 nil，信号继续向外传递
+func branchTarget(loops []*loopTarget, signal any) (*loopTarget, int) {
+	var label string
+	isBreak := false
+	switch r := signal.(type) {
+	case breakSentinel:
+		label, isBreak = r.label, true
+	case continueSentinel:
+		label = r.label
+	default:
+		return nil, 0
+	}
+	for idx := len(loops) - 1; idx >= 0; idx-- {
+		loop := loops[idx]
+		if label != "" && label != loop.label {
+			continue
+		}
+		if isBreak {
+			return loop, loop.breakPC
+		}
+		return loop, loop.continuePC
+	}
+	return nil, 0
+}
+
+// 退回到第 depth 层作用域
+func restore(i *Interpreter, scopes []*Interpreter, depth int) (*Interpreter, []*Interpreter) {
+	if len(scopes) > depth {
+		return scopes[depth], scopes[:depth]
+	}
+	return i, scopes
+}